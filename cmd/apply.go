@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ninogresenz/civar/service"
+)
+
+var applyCmd = &cobra.Command{
+	Use:     "apply {group/project | --group group | --instance}",
+	Example: "cat .env | civar apply group/project -d\ncivar apply group/project -F vars.json -f json --prune",
+	Short:   "Applies CI/CD variables",
+	Long: "Reads data from stdin or file and makes it the source of truth for a Gitlab project: " +
+		"missing variables are created and changed variables are updated. " +
+		"With --prune, variables not present in the input are deleted.",
+	Args: targetArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		api, err := newApi()
+		if err != nil {
+			return err
+		}
+		service := service.NewService(api)
+		plan, err := service.Apply(cmd.Context(), cmd.OutOrStdout(), getTarget(args), newInput(cmd), prune, dryRun)
+		if err != nil {
+			return err
+		}
+		if !dryRun {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "created: %d, updated: %d, deleted: %d, unchanged: %d\n",
+				len(plan.Creates), len(plan.Updates), len(plan.Deletes), len(plan.Unchanged))
+			reportHidden(cmd, plan)
+		}
+		return checkPendingChanges(plan)
+	},
+}
+
+func init() {
+	applyCmd.Flags().StringVarP(&format, "format", "f", "dotenv", "format is one of [ json | dotenv | yaml ]")
+	applyCmd.Flags().BoolVarP(&k8s, "k8s", "k", false, "applies variables with K8S_SECRET_ prefix")
+	applyCmd.Flags().StringVarP(&fileFlag, "file", "F", "", "reads input from a file")
+	applyCmd.Flags().BoolVar(&prune, "prune", false, "deletes variables not present in the input")
+	applyCmd.Flags().BoolVar(&dryRun, "dry-run", false, "prints the plan without changing variables, exits with code 2 if changes are pending")
+	addTargetFlags(applyCmd, "applies")
+	addScopeMappingFlags(applyCmd)
+	addKeyTransformFlag(applyCmd)
+	rootCmd.AddCommand(applyCmd)
+}
@@ -1,41 +1,197 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
+	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/ninogresenz/civar/gitlab"
+	"github.com/ninogresenz/civar/service"
 )
 
-func getToken() string {
-	token = viper.GetString("token")
-	if token == "" {
-		token = viper.GetString("GITLAB_TOKEN")
+// newApi creates the Api with the connection settings of the flags and the config file.
+func newApi() (gitlab.Api, error) {
+	return connectionOf(viper.GetViper()).api()
+}
+
+// connection holds the settings needed to reach a Gitlab instance.
+type connection struct {
+	url    string
+	token  string
+	client gitlab.ClientConfig
+}
+
+// connectionOf reads the connection settings of a config.
+func connectionOf(v *viper.Viper) connection {
+	url := v.GetString("url")
+	if url == "" {
+		url = v.GetString("GITLAB_URL")
 	}
+	token := v.GetString("token")
 	if token == "" {
-		fmt.Printf("No Gitlab token found. You can set it via 3 options:\n" +
-			"* set flag '--token xxx'\n" +
-			"* export GITLAB_TOKEN=xxx\n" +
-			"* set token property in $HOME/.civar.yml\n")
-		os.Exit(1)
+		token = v.GetString("GITLAB_TOKEN")
+	}
+	return connection{
+		url:   strings.TrimSuffix(url, "/"),
+		token: token,
+		client: gitlab.ClientConfig{
+			CACert:             v.GetString("ca_cert"),
+			ClientCert:         v.GetString("client_cert"),
+			ClientKey:          v.GetString("client_key"),
+			InsecureSkipVerify: v.GetBool("insecure_skip_verify"),
+			Proxy:              v.GetString("proxy"),
+			Timeout:            v.GetDuration("timeout"),
+		},
 	}
-	return token
 }
 
-func getGitlabUrl() string {
-	url := viper.GetString("url")
-	if url == "" {
-		url = viper.GetString("GITLAB_URL")
+// contextConnection reads the connection settings of a named context, ignoring the current one.
+func contextConnection(name string) (connection, error) {
+	settings, ok := contextSettings(name)
+	if !ok {
+		return connection{}, fmt.Errorf("context %q not found in %s", name, viper.ConfigFileUsed())
 	}
-	if url == "" {
-		fmt.Printf("No Gitlab url found. You can set it via 3 options:" +
-			"* set flag '--url xxx'" +
-			"* export GITLAB_URL=xxx" +
-			"* set url property in $HOME/.civar.yml")
-		os.Exit(1)
+	v := viper.New()
+	if err := v.MergeConfigMap(settings); err != nil {
+		return connection{}, err
+	}
+	return connectionOf(v), nil
+}
+
+// api connects to the Gitlab instance, url and token are required.
+func (c connection) api() (gitlab.Api, error) {
+	if c.url == "" {
+		return nil, errors.New("no Gitlab url found, set it with --url, GITLAB_URL, the url property in $HOME/.civar.yml or a context")
 	}
-	if url[len(url)-1:] == "/" {
-		return url[:len(url)-1]
+	if c.token == "" {
+		return nil, errors.New("no Gitlab token found, set it with --token, GITLAB_TOKEN, the token property in $HOME/.civar.yml or a context")
 	}
-	return url
+	httpClient, err := gitlab.NewHttpClient(c.client)
+	if err != nil {
+		return nil, err
+	}
+	var options []gitlab.Option
+	if verbose {
+		options = append(options, gitlab.WithLog(os.Stderr))
+	}
+	return gitlab.New(c.url, c.token, httpClient, options...), nil
+}
+
+// inputFormats are read by create, update, apply, diff and delete, outputFormats are printed by get.
+var (
+	inputFormats  = []string{"json", "dotenv", "yaml"}
+	outputFormats = []string{"json", "dotenv", "yaml", "pretty", "wide", "terraform", "kubernetes"}
+)
+
+// flagGiven reports whether a flag was given on the command line or defaulted by the context.
+func flagGiven(cmd *cobra.Command, name string) bool {
+	flag := cmd.Flags().Lookup(name)
+	_, defaulted := flag.Annotations[contextDefaultAnnotation]
+	return flag.Changed || defaulted
+}
+
+// newInput describes the variables read by create, update, apply, diff and delete.
+func newInput(cmd *cobra.Command) service.Input {
+	return service.Input{
+		Format: format,
+		K8s:    k8s,
+		File:   fileFlag,
+		Stdin:  cmd.InOrStdin(),
+
+		ScopeMappings:    scopeMappings,
+		ScopeMappingFile: scopeMappingFile,
+		KeyTransforms:    transformSpecs,
+	}
+}
+
+// addKeyTransformFlag registers the flag rewriting the keys of the input or output.
+func addKeyTransformFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&transformSpecs, "transform", nil,
+		"rewrites keys, one of add-prefix=X, strip-prefix=X, add-suffix=X, strip-suffix=X, rename=regex=replacement, upper, lower, can be repeated")
+}
+
+// addScopeMappingFlags registers the flags rewriting the environment scopes of the input.
+func addScopeMappingFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&scopeMappings, "map-scope", nil, "rewrites an environment scope before writing, e.g. staging=review/*, can be repeated")
+	cmd.Flags().StringVar(&scopeMappingFile, "map-scope-file", "", "reads scope mappings from a file, one from=to pair per line")
+}
+
+// errPendingChanges signals a dry run or diff which found changes.
+var errPendingChanges = errors.New("changes pending")
+
+// exitPendingChanges is the exit code of a dry run which found pending changes.
+const exitPendingChanges = 2
+
+func checkPendingChanges(plan service.Plan) error {
+	if dryRun && plan.HasChanges() {
+		return errPendingChanges
+	}
+	return nil
+}
+
+// reportHidden names the hidden variables of the plan which were skipped.
+func reportHidden(cmd *cobra.Command, plan service.Plan) {
+	for _, variable := range plan.Hidden {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "skipped hidden variable [key: %s, scope: %s]\n", variable.Key, variable.EnvironmentScope)
+	}
+}
+
+// exitInterrupted is the exit code of a command stopped by SIGINT or SIGTERM.
+const exitInterrupted = 130
+
+// exitStatus prints the error with a hint for common Gitlab API errors and returns the exit code.
+func exitStatus(w io.Writer, err error) int {
+	switch {
+	case errors.Is(err, errPendingChanges):
+		return exitPendingChanges
+	case errors.Is(err, service.ErrAborted):
+		_, _ = fmt.Fprintln(w, "aborted")
+		return 1
+	}
+	var interrupted *service.InterruptedError
+	if errors.As(err, &interrupted) {
+		_, _ = fmt.Fprintln(w, "Error:", err)
+		_, _ = fmt.Fprintln(w, interrupted.Summary())
+		return exitInterrupted
+	}
+	if errors.Is(err, context.Canceled) {
+		_, _ = fmt.Fprintln(w, "interrupted")
+		return exitInterrupted
+	}
+	_, _ = fmt.Fprintln(w, "Error:", err)
+	if hint := gitlab.Hint(err); len(hint) > 0 {
+		_, _ = fmt.Fprintln(w, "Hint:", hint)
+	}
+	return 1
+}
+
+// targetArgs expects a project as argument unless --group or --instance is given.
+func targetArgs(cmd *cobra.Command, args []string) error {
+	if group != "" || instance {
+		return cobra.NoArgs(cmd, args)
+	}
+	return cobra.ExactArgs(1)(cmd, args)
+}
+
+func getTarget(args []string) service.Target {
+	if instance {
+		return service.Target{Kind: service.InstanceTarget}
+	}
+	if group != "" {
+		return service.Target{Kind: service.GroupTarget, Path: group}
+	}
+	return service.Target{Kind: service.ProjectTarget, Path: args[0]}
+}
+
+// addTargetFlags registers the flags selecting a group or the instance instead of a project.
+func addTargetFlags(cmd *cobra.Command, verb string) {
+	cmd.Flags().StringVarP(&group, "group", "g", "", verb+" variables of a Gitlab group instead of a project")
+	cmd.Flags().BoolVar(&instance, "instance", false, verb+" instance variables (requires an administrator token)")
+	cmd.MarkFlagsMutuallyExclusive("group", "instance")
 }
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// contextConnectionKeys are the connection settings of a context.
+// They replace the top level settings of the config file, so a token is never sent to the url of another context.
+var contextConnectionKeys = []string{"url", "token", "ca_cert", "client_cert", "client_key", "insecure_skip_verify", "proxy", "timeout"}
+
+// contextFlagDefaults are the flags a context provides defaults for.
+var contextFlagDefaults = []string{"format", "k8s"}
+
+// contextDefaultAnnotation marks a flag whose default was set by the context.
+const contextDefaultAnnotation = "civar_context_default"
+
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Manages named Gitlab contexts of the config file",
+	Long: "A context bundles the url, token and connection settings of a Gitlab instance " +
+		"with defaults for flags like --format and --k8s. " +
+		"Contexts are defined under 'contexts' in the config file, " +
+		"the context used by default is stored as 'current-context'.",
+	// the context commands must work even if the current context is broken
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		cmd.SilenceUsage = true
+	},
+}
+
+var contextListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists all contexts, the current one is marked with *",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		current := currentContext()
+		for _, name := range contextNames() {
+			marker := " "
+			if name == current {
+				marker = "*"
+			}
+			settings, _ := contextSettings(name)
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s %s  %v\n", marker, name, settings["url"])
+		}
+		return nil
+	},
+}
+
+var contextCurrentCmd = &cobra.Command{
+	Use:   "current",
+	Short: "Prints the name of the current context",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		current := currentContext()
+		if current == "" {
+			return errors.New("no current context set")
+		}
+		_, err := fmt.Fprintln(cmd.OutOrStdout(), current)
+		return err
+	},
+}
+
+var contextUseCmd = &cobra.Command{
+	Use:     "use name",
+	Example: "civar context use gitlab.com",
+	Short:   "Makes the given context the default one",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, ok := contextSettings(args[0]); !ok {
+			return fmt.Errorf("context %q not found in %s", args[0], viper.ConfigFileUsed())
+		}
+		if err := setCurrentContext(viper.ConfigFileUsed(), strings.ToLower(args[0])); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(cmd.OutOrStdout(), "Switched to context %q.\n", args[0])
+		return err
+	},
+}
+
+func init() {
+	contextCmd.AddCommand(contextListCmd, contextCurrentCmd, contextUseCmd)
+	rootCmd.AddCommand(contextCmd)
+}
+
+// currentContext returns the context selected by --context or the current-context of the config file.
+func currentContext() string {
+	if contextName != "" {
+		return contextName
+	}
+	return viper.GetString("current-context")
+}
+
+func contextNames() []string {
+	var names []string
+	for name := range viper.GetStringMap("contexts") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// contextSettings returns the settings of the named context. The names are looked up
+// in the map of contexts, since names like gitlab.com are no valid viper key paths.
+// Like all config keys, they are case insensitive.
+func contextSettings(name string) (map[string]interface{}, bool) {
+	settings, ok := viper.GetStringMap("contexts")[strings.ToLower(name)].(map[string]interface{})
+	return settings, ok
+}
+
+// useContext applies the settings of the current context. Its connection settings replace
+// the top level ones of the config file, while flags and environment variables still take precedence.
+// Its defaults apply to the flags of cmd which are not given on the command line.
+func useContext(cmd *cobra.Command) error {
+	name := currentContext()
+	if name == "" {
+		return nil
+	}
+	settings, ok := contextSettings(name)
+	if !ok {
+		return fmt.Errorf("context %q not found in %s", name, viper.ConfigFileUsed())
+	}
+	connection := map[string]interface{}{}
+	for _, key := range contextConnectionKeys {
+		connection[key] = settings[key]
+	}
+	if err := viper.MergeConfigMap(connection); err != nil {
+		return err
+	}
+	for _, key := range contextFlagDefaults {
+		value, ok := settings[key]
+		flag := cmd.Flags().Lookup(key)
+		if !ok || flag == nil || flag.Changed || !validDefault(cmd, key, fmt.Sprint(value)) {
+			continue
+		}
+		// the default is replaced without marking the flag as given, so alias flags like -p still win
+		if err := flag.Value.Set(fmt.Sprint(value)); err != nil {
+			return fmt.Errorf("invalid %s of context %q: %w", key, name, err)
+		}
+		flag.DefValue = flag.Value.String()
+		if err := cmd.Flags().SetAnnotation(key, contextDefaultAnnotation, []string{name}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validDefault reports whether the context default of a flag is valid for cmd. The format of get
+// is an output format, the other commands read one of the input formats.
+func validDefault(cmd *cobra.Command, key string, value string) bool {
+	if key != "format" {
+		return true
+	}
+	formats := inputFormats
+	if cmd == getCmd {
+		formats = outputFormats
+	}
+	for _, format := range formats {
+		if value == format {
+			return true
+		}
+	}
+	return false
+}
+
+// setCurrentContext stores the current-context in the config file and keeps the rest of it including comments.
+func setCurrentContext(configFile string, name string) error {
+	if configFile == "" {
+		return errors.New("no config file found, create $HOME/.civar.yml first")
+	}
+	info, err := os.Stat(configFile)
+	if err != nil {
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return fmt.Errorf("could not parse config file: %w", err)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("config file %s is not a yaml map", configFile)
+	}
+	root := document.Content[0]
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}
+	replaced := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "current-context" {
+			root.Content[i+1] = value
+			replaced = true
+		}
+	}
+	if !replaced {
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "current-context"}
+		root.Content = append([]*yaml.Node{key, value}, root.Content...)
+	}
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return err
+	}
+	return os.WriteFile(configFile, out.Bytes(), info.Mode().Perm())
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ninogresenz/civar/gitlab"
+	"github.com/ninogresenz/civar/service"
+)
+
+var fromContext string
+var fromUrl string
+var fromToken string
+var toContext string
+var toUrl string
+var toToken string
+var overwrite bool
+
+var copyCmd = &cobra.Command{
+	Use: "copy source destination",
+	Example: "civar copy apps/project1 apps/project2\n" +
+		"civar copy apps/project1 group:apps --scope staging\n" +
+		"civar copy apps/project1 apps/project1 --from-context gitlab.com --to-context internal",
+	Short: "Copies CI/CD variables between projects, groups and Gitlab instances",
+	Long: "Copies all variables of the source to the destination with all their attributes. " +
+		"Source and destination are a project path, group:<path> or instance. " +
+		"Each side connects with its own context, url and token and falls back to the current ones. " +
+		"A url without a token is only accepted if it matches the context. " +
+		"Existing variables are skipped unless --overwrite is given. " +
+		"Hidden variables are skipped.",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		source, err := sideApi(fromContext, fromUrl, fromToken)
+		if err != nil {
+			return fmt.Errorf("source: %w", err)
+		}
+		destination, err := sideApi(toContext, toUrl, toToken)
+		if err != nil {
+			return fmt.Errorf("destination: %w", err)
+		}
+		return runCopy(cmd, source, parseTarget(args[0]), destination, parseTarget(args[1]))
+	},
+}
+
+func init() {
+	copyCmd.Flags().StringVar(&fromContext, "from-context", "", "context of the source (default is the current context)")
+	copyCmd.Flags().StringVar(&fromUrl, "from-url", "", "gitlab url of the source")
+	copyCmd.Flags().StringVar(&fromToken, "from-token", "", "token of the source")
+	copyCmd.Flags().StringVar(&toContext, "to-context", "", "context of the destination (default is the current context)")
+	copyCmd.Flags().StringVar(&toUrl, "to-url", "", "gitlab url of the destination")
+	copyCmd.Flags().StringVar(&toToken, "to-token", "", "token of the destination")
+	copyCmd.Flags().StringVarP(&scopeFilter, "scope", "s", "", "copies only the variables of this scope")
+	copyCmd.Flags().BoolVar(&overwrite, "overwrite", false, "updates variables which already exist in the destination")
+	copyCmd.Flags().BoolVar(&dryRun, "dry-run", false, "prints the plan without changing variables, exits with code 2 if changes are pending")
+	addScopeMappingFlags(copyCmd)
+	addKeyTransformFlag(copyCmd)
+	rootCmd.AddCommand(copyCmd)
+}
+
+func runCopy(cmd *cobra.Command, source gitlab.Api, from service.Target, destination gitlab.Api, to service.Target) error {
+	options := service.CopyOptions{
+		Scope:            scopeFilter,
+		Overwrite:        overwrite,
+		DryRun:           dryRun,
+		ScopeMappings:    scopeMappings,
+		ScopeMappingFile: scopeMappingFile,
+		KeyTransforms:    transformSpecs,
+	}
+	plan, err := service.Copy(cmd.Context(), cmd.OutOrStdout(), service.NewService(source), from, service.NewService(destination), to, options)
+	if err != nil {
+		return err
+	}
+	if !dryRun {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Copied %d, updated %d, skipped %d variables from %s to %s\n",
+			len(plan.Creates), len(plan.Updates), len(plan.Skips)+len(plan.Hidden), from, to)
+		for _, variable := range plan.Skips {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "skipped existing variable [key: %s, scope: %s]\n", variable.Key, variable.EnvironmentScope)
+		}
+		reportHidden(cmd, plan)
+	}
+	return checkPendingChanges(plan)
+}
+
+// sideApi connects to one side of a copy. The named context replaces the current one,
+// url and token replace the ones of the context. A url differing from the one of the
+// context requires a token, so the token of the context is never sent to another host.
+func sideApi(contextName string, url string, token string) (gitlab.Api, error) {
+	c := connectionOf(viper.GetViper())
+	if contextName != "" {
+		var err error
+		c, err = contextConnection(contextName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if url != "" {
+		url = strings.TrimSuffix(url, "/")
+		if url != c.url && token == "" {
+			return nil, fmt.Errorf("a token is required for %s, set it together with the url or use a context", url)
+		}
+		c.url = url
+	}
+	if token != "" {
+		c.token = token
+	}
+	return c.api()
+}
+
+// parseTarget reads a reference like group/project, group:group or instance.
+func parseTarget(reference string) service.Target {
+	if reference == "instance" {
+		return service.Target{Kind: service.InstanceTarget}
+	}
+	if strings.HasPrefix(reference, "group:") {
+		return service.Target{Kind: service.GroupTarget, Path: strings.TrimPrefix(reference, "group:")}
+	}
+	return service.Target{Kind: service.ProjectTarget, Path: reference}
+}
@@ -1,32 +1,48 @@
 package cmd
 
 import (
-	"net/http"
+	"fmt"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
-	"github.com/ninogresenz/civar/gitlab"
 	"github.com/ninogresenz/civar/service"
 )
 
 var createCmd = &cobra.Command{
-	Use:     "create group/project",
-	Example: "cat .env | civar create group/project -d",
+	Use:     "create {group/project | --group group | --instance}",
+	Example: "cat .env | civar create group/project -d\ncat .env | civar create --group group -d",
 	Short:   "Creates CI/CD variables",
 	Long:    "Reads data from stdin or file and creates all variables in a Gitlab project. Already existent variables will be skipped.",
-	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		api := gitlab.New(getGitlabUrl(), getToken(), http.DefaultClient)
-		service := service.NewService(api, cmd, args)
-		service.Create(format, k8s, fileFlag)
+	Args:    targetArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		api, err := newApi()
+		if err != nil {
+			return err
+		}
+		service := service.NewService(api)
+		plan, err := service.Create(cmd.Context(), cmd.OutOrStdout(), getTarget(args), newInput(cmd), dryRun)
+		if err != nil {
+			return err
+		}
+		if !dryRun && len(plan.Skips) > 0 {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Duplicate variables skipped: %d/%d\n", len(plan.Skips), len(plan.Creates)+len(plan.Skips))
+		}
+		if !dryRun {
+			reportHidden(cmd, plan)
+		}
+		return checkPendingChanges(plan)
 	},
 }
 
 func init() {
-	createCmd.Flags().StringVarP(&format, "format", "f", "dotenv", "format is one of [ json | dotenv ]")
+	createCmd.Flags().StringVarP(&format, "format", "f", "dotenv", "format is one of [ json | dotenv | yaml ]")
 	_ = viper.BindPFlag("format", createCmd.Flags().Lookup("format"))
 	createCmd.Flags().BoolVarP(&k8s, "k8s", "k", false, "creates variables with K8S_SECRET_ prefix")
 	createCmd.Flags().StringVarP(&fileFlag, "file", "F", "", "reads input from a file")
+	createCmd.Flags().BoolVar(&dryRun, "dry-run", false, "prints the plan without changing variables, exits with code 2 if changes are pending")
+	addTargetFlags(createCmd, "creates")
+	addScopeMappingFlags(createCmd)
+	addKeyTransformFlag(createCmd)
 	rootCmd.AddCommand(createCmd)
 }
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ninogresenz/civar/gitlab"
+	"github.com/ninogresenz/civar/service"
+)
+
+var deleteCmd = &cobra.Command{
+	Use: "delete {group/project | --group group | --instance} [key...]",
+	Example: "civar delete group/project VAR_1 VAR_2\n" +
+		"civar delete group/project --scope staging\n" +
+		"cat .env | civar delete group/project -d --yes",
+	Short: "Deletes CI/CD variables",
+	Long: "Deletes the given keys or all variables matching --key and --scope. " +
+		"Without keys or filters, variables are read from stdin or file and deleted if they exist. " +
+		"Asks for confirmation unless --yes is given.",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if group != "" || instance {
+			return nil
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target := getTarget(args)
+		keyArgs := args
+		if target.Kind == service.ProjectTarget {
+			keyArgs = args[1:]
+		}
+		options := service.DeleteOptions{
+			Keys:  append(keyArgs, keys...),
+			Scope: scopeFilter,
+			K8s:   k8s,
+			Input: newInput(cmd),
+		}
+		if !yes {
+			options.Confirm = confirmDelete(cmd, len(options.Keys) == 0 && len(options.Scope) == 0 && len(fileFlag) == 0)
+		}
+		api, err := newApi()
+		if err != nil {
+			return err
+		}
+		service := service.NewService(api)
+		plan, err := service.Delete(cmd.Context(), target, options)
+		if len(plan.Skips) > 0 {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "variables skipped because not existent: %d/%d\n", len(plan.Skips), len(plan.Deletes)+len(plan.Skips))
+		}
+		if err != nil {
+			return err
+		}
+		if len(plan.Deletes) == 0 {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "no variables to delete")
+			return nil
+		}
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "variables deleted: %d\n", len(plan.Deletes))
+		return nil
+	},
+}
+
+func init() {
+	deleteCmd.Flags().StringSliceVar(&keys, "key", nil, "deletes variables with this key, can be repeated")
+	deleteCmd.Flags().StringVarP(&scopeFilter, "scope", "s", "", "deletes only variables of this scope")
+	deleteCmd.Flags().StringVarP(&format, "format", "f", "dotenv", "format is one of [ json | dotenv | yaml ]")
+	deleteCmd.Flags().BoolVarP(&k8s, "k8s", "k", false, "deletes variables with K8S_SECRET_ prefix")
+	deleteCmd.Flags().StringVarP(&fileFlag, "file", "F", "", "reads input from a file")
+	deleteCmd.Flags().BoolVarP(&yes, "yes", "y", false, "deletes without asking for confirmation")
+	addTargetFlags(deleteCmd, "deletes")
+	rootCmd.AddCommand(deleteCmd)
+}
+
+// confirmDelete lists the variables and asks the user for confirmation.
+// If stdin already provided the input, the answer is read from the terminal instead.
+func confirmDelete(cmd *cobra.Command, readsStdin bool) func(service.Target, gitlab.CiVariableList) (bool, error) {
+	return func(target service.Target, data gitlab.CiVariableList) (bool, error) {
+		var answers io.Reader = cmd.InOrStdin()
+		if readsStdin {
+			tty, err := os.Open("/dev/tty")
+			if err != nil {
+				return false, errors.New("could not ask for confirmation, use --yes to delete variables read from stdin")
+			}
+			defer tty.Close()
+			answers = tty
+		}
+		for _, variable := range data {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s\t%s\n", variable.EnvironmentScope, variable.Key)
+		}
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Delete %d variables from %s? [y/N] ", len(data), target)
+		answer, _ := bufio.NewReader(answers).ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		return answer == "y" || answer == "yes", nil
+	}
+}
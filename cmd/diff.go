@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/ninogresenz/civar/service"
+)
+
+var diffCmd = &cobra.Command{
+	Use: "diff {group/project | --group group | --instance}",
+	Example: "civar diff group/project -F .env\n" +
+		"cat vars.json | civar diff group/project -f json -o json\n" +
+		"civar diff group/project --against group/other-project\n" +
+		"civar diff group/project --from-scope staging --to-scope production",
+	Short: "Compares CI/CD variables with a file, another project or another scope",
+	Long: "Reads data from stdin or file and prints the added, removed and changed variables per scope " +
+		"compared to the variables of a Gitlab project. Nothing is changed.\n" +
+		"With --against, the variables of a second project (or group with --group) are compared instead. " +
+		"With --from-scope and --to-scope, the variables of two scopes are compared by key.",
+	Args: targetArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		api, err := newApi()
+		if err != nil {
+			return err
+		}
+		service := service.NewService(api)
+		diff, err := runDiff(cmd, service, getTarget(args))
+		if err != nil {
+			return err
+		}
+		if exitCode && diff.HasChanges() {
+			return errPendingChanges
+		}
+		return nil
+	},
+}
+
+func init() {
+	diffCmd.Flags().StringVarP(&format, "format", "f", "dotenv", "input format is one of [ json | dotenv | yaml ]")
+	diffCmd.Flags().StringVarP(&output, "output", "o", "text", "output format is one of [ text | json ]")
+	diffCmd.Flags().BoolVarP(&k8s, "k8s", "k", false, "compares variables with K8S_SECRET_ prefix")
+	diffCmd.Flags().StringVarP(&fileFlag, "file", "F", "", "reads input from a file")
+	diffCmd.Flags().StringVar(&against, "against", "", "compares with the variables of another project or group")
+	diffCmd.Flags().StringVar(&fromScope, "from-scope", "", "compares only variables of this scope")
+	diffCmd.Flags().StringVar(&toScope, "to-scope", "", "compares with variables of this scope, defaults to --from-scope")
+	diffCmd.Flags().BoolVar(&exitCode, "exit-code", false, "exits with code 2 if there are differences")
+	addTargetFlags(diffCmd, "compares")
+	diffCmd.MarkFlagsMutuallyExclusive("against", "instance")
+	diffCmd.MarkFlagsMutuallyExclusive("against", "file")
+	addKeyTransformFlag(diffCmd)
+	rootCmd.AddCommand(diffCmd)
+}
+
+func runDiff(cmd *cobra.Command, s service.Service, target service.Target) (service.Diff, error) {
+	if len(against) == 0 && len(fromScope) == 0 && len(toScope) == 0 {
+		return s.Diff(cmd.Context(), cmd.OutOrStdout(), target, newInput(cmd), output)
+	}
+	other := target
+	if len(against) > 0 {
+		other.Path = against
+	}
+	return s.DiffTargets(cmd.Context(), cmd.OutOrStdout(), target, other, fromScope, toScope, output)
+}
@@ -1,50 +1,126 @@
 package cmd
 
 import (
-	"net/http"
+	"errors"
+	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
-	"github.com/ninogresenz/civar/gitlab"
 	"github.com/ninogresenz/civar/service"
 )
 
 // getCmd represents the get command
 var getCmd = &cobra.Command{
-	Use:     "get group/project",
-	Example: "civar get group/project -d",
+	Use:     "get {group/project | --group group | --instance}",
+	Example: "civar get group/project -d\ncivar get --group group -d",
 	Short:   "Shows CI/CD variables",
 	Long:    "Prints CI/CD variables for the given Gitlab project to stdout.",
-	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		api := gitlab.New(getGitlabUrl(), getToken(), http.DefaultClient)
-		service := service.NewService(api, cmd, args)
+	Args:    targetArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if pretty {
 			format = "pretty"
 		}
 		if dotenv {
 			format = "dotenv"
 		}
-		service.Get(format, scopeFilter)
+		if wide {
+			format = "wide"
+		}
+		transforms, err := getTransforms(cmd)
+		if err != nil {
+			return err
+		}
+		api, err := newApi()
+		if err != nil {
+			return err
+		}
+		service := service.NewService(api)
+		return runGet(cmd, service, getTarget(args), transforms)
 	},
 }
 
 func init() {
-	getCmd.Flags().StringVarP(&scopeFilter, "scope", "s", "", "scope filter [ * | staging | production | prodtest ]")
+	getCmd.Flags().StringVarP(&scopeFilter, "scope", "s", "", "scope filter, e.g. [ * | staging | production ]")
 
-	getCmd.Flags().StringVarP(&format, "format", "f", "dotenv", "format is one of [ json | dotenv | pretty ]")
+	getCmd.Flags().StringVarP(&format, "format", "f", "dotenv", "format is one of [ json | dotenv | yaml | pretty | wide | terraform | kubernetes ]")
 	_ = viper.BindPFlag("format", getCmd.Flags().Lookup("format"))
 
 	// TODO therse should become format
 	getCmd.Flags().BoolVarP(&pretty, "pretty", "p", false, "alias for --format pretty")
 	getCmd.Flags().BoolVarP(&dotenv, "dotenv", "d", false, "alias for --format dotenv")
+	getCmd.Flags().BoolVarP(&wide, "wide", "w", false, "alias for --format wide, a table with all attributes")
 
 	// make sure they're not used together
 	getCmd.MarkFlagsMutuallyExclusive(
 		"format",
 		"pretty",
 		"dotenv",
+		"wide",
 	)
+	getCmd.Flags().StringVar(&tfvarsFile, "tfvars", "", "with --format terraform: writes the values to this tfvars file instead of inlining them")
+	getCmd.Flags().StringVar(&manifestName, "name", "", "with --format kubernetes: name of the Secret and ConfigMap (default from the project)")
+	getCmd.Flags().StringVar(&namespace, "namespace", "", "with --format kubernetes: namespace of the Secret and ConfigMap")
+	getCmd.Flags().StringToStringVar(&labels, "label", nil, "with --format kubernetes: labels like app=web, can be repeated")
+	getCmd.Flags().BoolVar(&configMap, "configmap", false, "with --format kubernetes: puts values which are neither masked nor hidden into a ConfigMap")
+	getCmd.Flags().BoolVarP(&k8s, "k8s", "k", false, "strips the K8S_SECRET_ prefix from keys (default true for dotenv and kubernetes)")
+	addKeyTransformFlag(getCmd)
+	addTargetFlags(getCmd, "shows")
 	rootCmd.AddCommand(getCmd)
 }
+
+func runGet(cmd *cobra.Command, s service.Service, target service.Target, transforms service.KeyTransforms) error {
+	options := service.GetOptions{
+		Format:     format,
+		Scope:      scopeFilter,
+		Transforms: transforms,
+		Kubernetes: service.KubernetesOptions{
+			Name:      manifestName,
+			Namespace: namespace,
+			Labels:    labels,
+			ConfigMap: configMap,
+		},
+	}
+	if len(tfvarsFile) > 0 {
+		if format != "terraform" {
+			return errors.New("--tfvars requires --format terraform")
+		}
+		file, err := os.OpenFile(tfvarsFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("could not create tfvars file: %w", err)
+		}
+		defer file.Close()
+		options.TfVars = file
+	}
+	data, err := s.Get(cmd.Context(), cmd.OutOrStdout(), target, options)
+	if err != nil {
+		return err
+	}
+	if format == "kubernetes" {
+		for _, variable := range data {
+			if variable.Hidden {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "left out hidden variable %s\n", transforms.Key(variable.Key))
+			}
+		}
+	}
+	return nil
+}
+
+// getTransforms strips the K8S_SECRET_ prefix from dotenv and kubernetes output unless --k8s=false is given
+// or set by the context.
+// The --transform flags are applied afterwards. The format flags must be resolved before.
+func getTransforms(cmd *cobra.Command) (service.KeyTransforms, error) {
+	strip := format == "dotenv" || format == "kubernetes"
+	if flagGiven(cmd, "k8s") {
+		strip = k8s
+	}
+	transforms, err := service.ParseKeyTransforms(transformSpecs)
+	if err != nil {
+		return nil, err
+	}
+	if strip {
+		transforms = append(service.K8sExport(), transforms...)
+	}
+	return transforms, nil
+}
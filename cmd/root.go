@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -11,21 +15,56 @@ import (
 )
 
 var cfgFile string
+var contextName string
 var token string
 var gitlabUrl string
+var verbose bool
+var caCert string
+var clientCert string
+var clientKey string
+var insecureSkipVerify bool
+var proxy string
+var timeout time.Duration
 
 var format string
 var pretty bool
 var dotenv bool
+var wide bool
 var scopeFilter string
 var k8s bool
 var fileFlag string
+var group string
+var instance bool
+var keys []string
+var yes bool
+var prune bool
+var dryRun bool
+var output string
+var exitCode bool
+var against string
+var fromScope string
+var toScope string
+var settings []string
+var scopeMappings []string
+var scopeMappingFile string
+var transformSpecs []string
+var tfvarsFile string
+var manifestName string
+var namespace string
+var labels map[string]string
+var configMap bool
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "civar",
 	Short: "Show / Create CI/CD Variables in Gitlab projects",
 	Long:  `CLI tool for fetching and creating CI/CD Variables in Gitlab projects`,
+	// usage is only printed for invalid flags and arguments, errors are printed by Execute
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return useContext(cmd)
+	},
 	Example: `- Print all CI/CD variables in a table
 	civar get -p 1
 	
@@ -40,8 +79,19 @@ var rootCmd = &cobra.Command{
 `,
 }
 
+// Execute runs the command until it is done or interrupted. The first SIGINT or SIGTERM
+// cancels the running requests, a second one terminates civar immediately.
 func Execute() {
-	cobra.CheckErr(rootCmd.Execute())
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if err != nil {
+		os.Exit(exitStatus(os.Stderr, err))
+	}
 }
 
 func init() {
@@ -51,6 +101,7 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.civar.yml)")
+	rootCmd.PersistentFlags().StringVar(&contextName, "context", "", "named context of the config file to use (default is current-context)")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -59,6 +110,26 @@ func init() {
 
 	rootCmd.PersistentFlags().StringVarP(&gitlabUrl, "url", "u", "", "sets your gitlab url")
 	_ = viper.BindPFlag("url", rootCmd.PersistentFlags().Lookup("url"))
+
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "prints details like retried requests to stderr")
+
+	rootCmd.PersistentFlags().StringVar(&caCert, "ca-cert", "", "PEM bundle of additional CAs trusted for the gitlab url")
+	_ = viper.BindPFlag("ca_cert", rootCmd.PersistentFlags().Lookup("ca-cert"))
+
+	rootCmd.PersistentFlags().StringVar(&clientCert, "client-cert", "", "PEM client certificate for mutual TLS")
+	_ = viper.BindPFlag("client_cert", rootCmd.PersistentFlags().Lookup("client-cert"))
+
+	rootCmd.PersistentFlags().StringVar(&clientKey, "client-key", "", "PEM key of the client certificate")
+	_ = viper.BindPFlag("client_key", rootCmd.PersistentFlags().Lookup("client-key"))
+
+	rootCmd.PersistentFlags().BoolVar(&insecureSkipVerify, "insecure-skip-verify", false, "does not verify the certificate of the gitlab url")
+	_ = viper.BindPFlag("insecure_skip_verify", rootCmd.PersistentFlags().Lookup("insecure-skip-verify"))
+
+	rootCmd.PersistentFlags().StringVar(&proxy, "proxy", "", "http, https or socks5 proxy url (default from HTTP_PROXY / HTTPS_PROXY)")
+	_ = viper.BindPFlag("proxy", rootCmd.PersistentFlags().Lookup("proxy"))
+
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "time limit per request including retries, e.g. 30s (default no limit)")
+	_ = viper.BindPFlag("timeout", rootCmd.PersistentFlags().Lookup("timeout"))
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -1,11 +1,8 @@
 package cmd
 
 import (
-	"net/http"
-
 	"github.com/spf13/cobra"
 
-	"github.com/ninogresenz/civar/gitlab"
 	"github.com/ninogresenz/civar/service"
 )
 
@@ -16,10 +13,14 @@ var searchCmd = &cobra.Command{
 	Short:   "Searches Gitlab for project names",
 	Long:    "Prints all Gitlab Projects for the given search term to stdout.",
 	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		api := gitlab.New(getGitlabUrl(), getToken(), http.DefaultClient)
-		service := service.NewService(api, cmd, args)
-		service.Search()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		api, err := newApi()
+		if err != nil {
+			return err
+		}
+		service := service.NewService(api)
+		_, err = service.Search(cmd.Context(), cmd.OutOrStdout(), args[0])
+		return err
 	},
 }
 
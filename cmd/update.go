@@ -1,32 +1,63 @@
 package cmd
 
 import (
-	"net/http"
+	"fmt"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
-	"github.com/ninogresenz/civar/gitlab"
 	"github.com/ninogresenz/civar/service"
 )
 
 var updateCmd = &cobra.Command{
-	Use:     "update group/project",
-	Example: "cat .env | civar update group/project -d",
-	Short:   "Updates CI/CD variables",
-	Long:    "Reads data from stdin or file and updates already existing variables in a Gitlab project. Non existent variables will be skipped.",
-	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		api := gitlab.New(getGitlabUrl(), getToken(), http.DefaultClient)
-		service := service.NewService(api, cmd, args)
-		service.Update(format, k8s, fileFlag)
+	Use: "update {group/project | --group group | --instance}",
+	Example: "cat .env | civar update group/project -d\n" +
+		"cat .env | civar update --group group -d\n" +
+		"civar update group/project --scope production --set protected=true",
+	Short: "Updates CI/CD variables",
+	Long: "Reads data from stdin or file and updates already existing variables in a Gitlab project. Non existent variables will be skipped.\n" +
+		"With --set, attributes of existing variables selected by --key and --scope are changed without changing their values.",
+	Args: targetArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		api, err := newApi()
+		if err != nil {
+			return err
+		}
+		service := service.NewService(api)
+		plan, err := runUpdate(cmd, service, getTarget(args))
+		if err != nil {
+			return err
+		}
+		if !dryRun {
+			if len(settings) > 0 {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "updated: %d, unchanged: %d\n", len(plan.Updates), len(plan.Unchanged))
+			} else if len(plan.Skips) > 0 {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "variables skipped because not existent: %d/%d\n", len(plan.Skips), len(plan.Updates)+len(plan.Unchanged)+len(plan.Skips))
+			}
+			reportHidden(cmd, plan)
+		}
+		return checkPendingChanges(plan)
 	},
 }
 
 func init() {
-	updateCmd.Flags().StringVarP(&format, "format", "f", "dotenv", "format is one of [ json | dotenv | pretty ]")
+	updateCmd.Flags().StringVarP(&format, "format", "f", "dotenv", "format is one of [ json | dotenv | yaml ]")
 	_ = viper.BindPFlag("format", createCmd.Flags().Lookup("format"))
 	updateCmd.Flags().BoolVarP(&k8s, "k8s", "k", false, "update variables with K8S_SECRET_ prefix")
 	updateCmd.Flags().StringVarP(&fileFlag, "file", "F", "", "reads input from a file")
+	updateCmd.Flags().StringArrayVar(&settings, "set", nil, "sets an attribute instead of reading input, e.g. protected=true, can be repeated")
+	updateCmd.Flags().StringSliceVar(&keys, "key", nil, "with --set: updates variables with this key, can be repeated")
+	updateCmd.Flags().StringVarP(&scopeFilter, "scope", "s", "", "with --set: updates only variables of this scope")
+	updateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "prints the plan without changing variables, exits with code 2 if changes are pending")
+	addTargetFlags(updateCmd, "updates")
+	addScopeMappingFlags(updateCmd)
+	addKeyTransformFlag(updateCmd)
 	rootCmd.AddCommand(updateCmd)
 }
+
+func runUpdate(cmd *cobra.Command, s service.Service, target service.Target) (service.Plan, error) {
+	if len(settings) > 0 {
+		return s.UpdateAttributes(cmd.Context(), cmd.OutOrStdout(), target, keys, scopeFilter, settings, dryRun)
+	}
+	return s.Update(cmd.Context(), cmd.OutOrStdout(), target, newInput(cmd), dryRun)
+}
@@ -1,7 +1,9 @@
 package gitlab
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 
@@ -11,10 +13,21 @@ import (
 // Api provides necessary methods to interact with the Gitlab's REST API.
 // Docs: https://docs.gitlab.com/ee/api/api_resources.html
 type Api interface {
-	Search(term string) ([]Project, error)
-	GetProjectVars(project string) (CiVariableList, error)
-	CreateVar(project string, variable CiVariable) (*CiVariable, error)
-	UpdateVar(project string, variable CiVariable) (*CiVariable, error)
+	Search(ctx context.Context, term string) ([]Project, error)
+	GetProjectVars(ctx context.Context, project string) (CiVariableList, error)
+	CreateVar(ctx context.Context, project string, variable CiVariable) (*CiVariable, error)
+	UpdateVar(ctx context.Context, project string, variable CiVariable) (*CiVariable, error)
+	DeleteVar(ctx context.Context, project string, variable CiVariable) error
+
+	GetGroupVars(ctx context.Context, group string) (CiVariableList, error)
+	CreateGroupVar(ctx context.Context, group string, variable CiVariable) (*CiVariable, error)
+	UpdateGroupVar(ctx context.Context, group string, variable CiVariable) (*CiVariable, error)
+	DeleteGroupVar(ctx context.Context, group string, variable CiVariable) error
+
+	GetInstanceVars(ctx context.Context) (CiVariableList, error)
+	CreateInstanceVar(ctx context.Context, variable CiVariable) (*CiVariable, error)
+	UpdateInstanceVar(ctx context.Context, variable CiVariable) (*CiVariable, error)
+	DeleteInstanceVar(ctx context.Context, variable CiVariable) error
 }
 
 type api struct {
@@ -22,31 +35,110 @@ type api struct {
 	api       *sling.Sling
 }
 
-func New(gitlabUrl string, token string, httpClient *http.Client) Api {
+type config struct {
+	retryPolicy RetryPolicy
+	log         io.Writer
+}
+
+// Option customizes the Api created by New.
+type Option func(*config)
+
+// WithRetryPolicy replaces the DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *config) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithLog writes verbose output like retries to w.
+func WithLog(w io.Writer) Option {
+	return func(c *config) {
+		c.log = w
+	}
+}
+
+// New creates an Api which retries requests failing with rate limits or transient errors.
+// The given httpClient is not modified.
+func New(gitlabUrl string, token string, httpClient *http.Client, options ...Option) Api {
+	c := config{retryPolicy: DefaultRetryPolicy}
+	for _, option := range options {
+		option(&c)
+	}
+	client := *httpClient
+	client.Transport = newRetryTransport(httpClient.Transport, c.retryPolicy, c.log)
 	return api{
 		gitlabUrl: gitlabUrl,
-		api:       sling.New().Base(gitlabUrl).Client(httpClient).Set("PRIVATE-TOKEN", token),
+		api:       sling.New().Base(gitlabUrl).Client(&client).Set("PRIVATE-TOKEN", token),
 	}
 }
 
-func (a api) Search(term string) (projects []Project, err error) {
+func (a api) Search(ctx context.Context, term string) (projects []Project, err error) {
 	req := a.api.New().Get(fmt.Sprintf("/api/v4/search?scope=projects&search=%s", term))
-	return paginate[Project](req, projects)
+	return paginate[Project](ctx, req, projects)
+}
+
+func (a api) GetProjectVars(ctx context.Context, project string) (allVars CiVariableList, err error) {
+	return a.getVars(ctx, projectPath(project))
+}
+
+func (a api) CreateVar(ctx context.Context, project string, variable CiVariable) (*CiVariable, error) {
+	return a.createVar(ctx, projectPath(project), variable)
+}
+
+func (a api) UpdateVar(ctx context.Context, project string, variable CiVariable) (*CiVariable, error) {
+	return a.updateVar(ctx, projectPath(project), variable)
+}
+
+// DeleteVar removes the variable matching the key and environment scope of the given variable.
+func (a api) DeleteVar(ctx context.Context, project string, variable CiVariable) error {
+	return a.deleteVar(ctx, projectPath(project), variable)
+}
+
+// Docs: https://docs.gitlab.com/ee/api/group_level_variables.html
+func (a api) GetGroupVars(ctx context.Context, group string) (CiVariableList, error) {
+	return a.getVars(ctx, groupPath(group))
 }
 
-func (a api) GetProjectVars(project string) (allVars CiVariableList, err error) {
-	projectEncoded := url.QueryEscape(project)
-	req := a.api.New().Get(fmt.Sprintf("/api/v4/projects/%s/variables", projectEncoded))
-	return paginate[CiVariable](req, allVars)
+func (a api) CreateGroupVar(ctx context.Context, group string, variable CiVariable) (*CiVariable, error) {
+	return a.createVar(ctx, groupPath(group), variable)
 }
 
-func (a api) CreateVar(project string, variable CiVariable) (created *CiVariable, err error) {
-	projectEncoded := url.QueryEscape(project)
+func (a api) UpdateGroupVar(ctx context.Context, group string, variable CiVariable) (*CiVariable, error) {
+	return a.updateVar(ctx, groupPath(group), variable)
+}
+
+func (a api) DeleteGroupVar(ctx context.Context, group string, variable CiVariable) error {
+	return a.deleteVar(ctx, groupPath(group), variable)
+}
+
+// Docs: https://docs.gitlab.com/ee/api/instance_level_ci_variables.html
+func (a api) GetInstanceVars(ctx context.Context) (CiVariableList, error) {
+	return a.getVars(ctx, instancePath)
+}
+
+func (a api) CreateInstanceVar(ctx context.Context, variable CiVariable) (*CiVariable, error) {
+	return a.createVar(ctx, instancePath, variable)
+}
+
+func (a api) UpdateInstanceVar(ctx context.Context, variable CiVariable) (*CiVariable, error) {
+	return a.updateVar(ctx, instancePath, variable)
+}
+
+func (a api) DeleteInstanceVar(ctx context.Context, variable CiVariable) error {
+	return a.deleteVar(ctx, instancePath, variable)
+}
+
+func (a api) getVars(ctx context.Context, path string) (allVars CiVariableList, err error) {
+	req := a.api.New().Get(path)
+	return paginate[CiVariable](ctx, req, allVars)
+}
+
+func (a api) createVar(ctx context.Context, path string, variable CiVariable) (created *CiVariable, err error) {
 	var errorResponse ErrorResponse
-	resp, err := a.api.New().
-		Post(fmt.Sprintf("/api/v4/projects/%v/variables", projectEncoded)).
-		BodyJSON(variable).
-		Receive(&created, &errorResponse)
+	req := a.api.New().
+		Post(path).
+		BodyJSON(NewCreateBody(variable))
+	resp, err := receive(ctx, req, &created, &errorResponse)
 	err = handleHttpError(resp, err, errorResponse)
 	if err != nil {
 		return nil, err
@@ -54,17 +146,36 @@ func (a api) CreateVar(project string, variable CiVariable) (created *CiVariable
 	return created, nil
 }
 
-func (a api) UpdateVar(project string, variable CiVariable) (updated *CiVariable, err error) {
-	projectEncoded := url.QueryEscape(project)
+func (a api) updateVar(ctx context.Context, path string, variable CiVariable) (updated *CiVariable, err error) {
 	var errorResponse ErrorResponse
-	body := UpdateBody{variable.Value, Filter{variable.EnvironmentScope}}
-	resp, err := a.api.New().
-		Put(fmt.Sprintf("/api/v4/projects/%v/variables/%v", projectEncoded, variable.Key)).
-		BodyForm(&body).
-		Receive(&updated, &errorResponse)
+	body := NewUpdateBody(variable)
+	req := a.api.New().
+		Put(fmt.Sprintf("%s/%v", path, variable.Key)).
+		BodyForm(&body)
+	resp, err := receive(ctx, req, &updated, &errorResponse)
 	err = handleHttpError(resp, err, errorResponse)
 	if err != nil {
 		return nil, err
 	}
 	return updated, nil
 }
+
+func (a api) deleteVar(ctx context.Context, path string, variable CiVariable) error {
+	var errorResponse ErrorResponse
+	query := DeleteQuery{Filter{variable.EnvironmentScope}}
+	req := a.api.New().
+		Delete(fmt.Sprintf("%s/%v", path, variable.Key)).
+		QueryStruct(&query)
+	resp, err := receive(ctx, req, nil, &errorResponse)
+	return handleHttpError(resp, err, errorResponse)
+}
+
+const instancePath = "/api/v4/admin/ci/variables"
+
+func projectPath(project string) string {
+	return fmt.Sprintf("/api/v4/projects/%s/variables", url.QueryEscape(project))
+}
+
+func groupPath(group string) string {
+	return fmt.Sprintf("/api/v4/groups/%s/variables", url.QueryEscape(group))
+}
@@ -0,0 +1,75 @@
+package gitlab
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// ClientConfig configures the connection to a Gitlab instance.
+type ClientConfig struct {
+	// CACert is the path of a PEM bundle trusted in addition to the system certificates
+	CACert string
+	// ClientCert and ClientKey are the paths of a PEM certificate and key for mutual TLS
+	ClientCert string
+	ClientKey  string
+	// InsecureSkipVerify disables the verification of the server certificate
+	InsecureSkipVerify bool
+	// Proxy is the URL of a http, https or socks5 proxy.
+	// The HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables are used if empty.
+	Proxy string
+	// Timeout limits each request including retries, zero means no limit
+	Timeout time.Duration
+}
+
+// NewHttpClient creates a client for New with the TLS, proxy and timeout settings of config.
+func NewHttpClient(config ClientConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+	if len(config.CACert) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(config.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA certificate: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", config.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if len(config.ClientCert) > 0 || len(config.ClientKey) > 0 {
+		if len(config.ClientCert) == 0 || len(config.ClientKey) == 0 {
+			return nil, errors.New("client certificate and key must be given together")
+		}
+		cert, err := tls.LoadX509KeyPair(config.ClientCert, config.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if len(config.Proxy) > 0 {
+		proxy, err := url.Parse(config.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("proxy scheme must be one of [http | https | socks5], got %q", proxy.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	return &http.Client{Transport: transport, Timeout: config.Timeout}, nil
+}
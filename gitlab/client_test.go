@@ -0,0 +1,63 @@
+package gitlab
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHttpClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	caCert := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}
+	assert.NoError(t, os.WriteFile(caCert, pem.EncodeToMemory(block), 0600))
+
+	tests := map[string]struct {
+		config  ClientConfig
+		success bool
+	}{
+		"untrusted":            {ClientConfig{}, false},
+		"ca cert":              {ClientConfig{CACert: caCert}, true},
+		"insecure skip verify": {ClientConfig{InsecureSkipVerify: true}, true},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			client, err := NewHttpClient(test.config)
+			assert.NoError(t, err)
+			resp, err := client.Get(server.URL)
+			if test.success {
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+
+	client, err := NewHttpClient(ClientConfig{Timeout: time.Second})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, client.Timeout)
+}
+
+func TestNewHttpClientInvalid(t *testing.T) {
+	tests := map[string]ClientConfig{
+		"missing ca cert":    {CACert: filepath.Join(t.TempDir(), "missing.pem")},
+		"client cert only":   {ClientCert: "cert.pem"},
+		"missing client key": {ClientCert: "cert.pem", ClientKey: "key.pem"},
+		"proxy scheme":       {Proxy: "ftp://proxy:21"},
+	}
+	for testName, config := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, err := NewHttpClient(config)
+			assert.Error(t, err)
+		})
+	}
+}
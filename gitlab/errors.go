@@ -0,0 +1,127 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Errors matched by ApiError via errors.Is.
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrRateLimited  = errors.New("rate limited")
+)
+
+// ApiError is returned for responses with a status code above 399.
+type ApiError struct {
+	StatusCode int
+	Method     string
+	URL        string
+	// Err and Message hold the error and message fields of the response body.
+	Err     string
+	Message string
+	// Validation maps attributes to their validation errors, e.g. "value": ["is invalid"].
+	Validation map[string][]string
+}
+
+func newApiError(response *http.Response, errorResponse ErrorResponse) *ApiError {
+	apiErr := &ApiError{
+		StatusCode: response.StatusCode,
+		Method:     response.Request.Method,
+		URL:        response.Request.URL.String(),
+		Err:        errorResponse.Error,
+	}
+	if len(errorResponse.Message) == 0 {
+		return apiErr
+	}
+	// the message is either a text or an object of validation errors
+	if err := json.Unmarshal(errorResponse.Message, &apiErr.Message); err == nil {
+		return apiErr
+	}
+	var validation map[string]json.RawMessage
+	if err := json.Unmarshal(errorResponse.Message, &validation); err != nil {
+		apiErr.Message = string(errorResponse.Message)
+		return apiErr
+	}
+	apiErr.Validation = make(map[string][]string)
+	for attribute, raw := range validation {
+		var messages []string
+		if err := json.Unmarshal(raw, &messages); err != nil {
+			messages = []string{strings.Trim(string(raw), `"`)}
+		}
+		apiErr.Validation[attribute] = messages
+	}
+	return apiErr
+}
+
+func (e *ApiError) Error() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("received status code [%d] on url: %s", e.StatusCode, e.URL))
+	if len(e.Err) > 0 {
+		b.WriteString(fmt.Sprintf("\nerror: %s", e.Err))
+	}
+	if len(e.Message) > 0 {
+		b.WriteString(fmt.Sprintf("\nmessage: %s", e.Message))
+	}
+	attributes := make([]string, 0, len(e.Validation))
+	for attribute := range e.Validation {
+		attributes = append(attributes, attribute)
+	}
+	sort.Strings(attributes)
+	for _, attribute := range attributes {
+		b.WriteString(fmt.Sprintf("\n%s: %s", attribute, strings.Join(e.Validation[attribute], ", ")))
+	}
+	return b.String()
+}
+
+func (e *ApiError) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return target == ErrBadRequest
+	case http.StatusUnauthorized:
+		return target == ErrUnauthorized
+	case http.StatusForbidden:
+		return target == ErrForbidden
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusConflict:
+		return target == ErrConflict
+	case http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	}
+	return false
+}
+
+// Hint explains how to solve common API errors. An empty string is returned for other errors.
+func Hint(err error) string {
+	var apiErr *ApiError
+	if !errors.As(err, &apiErr) {
+		return ""
+	}
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		return "the token is invalid or expired, create a new personal access token with the api scope"
+	case errors.Is(err, ErrForbidden):
+		return "the token lacks permissions, it needs the api scope and at least the Maintainer role"
+	case errors.Is(err, ErrNotFound) && strings.Contains(apiErr.Message, "Variable"):
+		return "the variable does not exist in this environment scope"
+	case errors.Is(err, ErrNotFound):
+		return "the project or group does not exist or is not visible to the token, check the path or use 'civar search'"
+	case errors.Is(err, ErrRateLimited):
+		return "the rate limit is still exceeded after retrying, try again later"
+	case apiErr.Validation["value"] != nil:
+		return "masked values need at least 8 characters, a single line and only characters of the Base64 alphabet"
+	case apiErr.Validation["key"] != nil && strings.Contains(strings.Join(apiErr.Validation["key"], ""), "taken"):
+		return "the variable already exists in this environment scope, use 'civar update' or 'civar apply'"
+	case apiErr.Validation["key"] != nil:
+		return "keys may only contain letters, digits and '_' and must not exceed 255 characters"
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApiError(t *testing.T) {
+	tests := map[string]struct {
+		status   int
+		body     string
+		sentinel error
+		message  string
+		hint     string
+	}{
+		"not found": {
+			status:   404,
+			body:     `{"message":"404 Project Not Found"}`,
+			sentinel: ErrNotFound,
+			message:  "404 Project Not Found",
+			hint:     "the project or group does not exist or is not visible to the token, check the path or use 'civar search'",
+		},
+		"forbidden": {
+			status:   403,
+			body:     `{"message":"403 Forbidden"}`,
+			sentinel: ErrForbidden,
+			message:  "403 Forbidden",
+			hint:     "the token lacks permissions, it needs the api scope and at least the Maintainer role",
+		},
+		"validation": {
+			status:   400,
+			body:     `{"message":{"value":["is invalid"]}}`,
+			sentinel: ErrBadRequest,
+			hint:     "masked values need at least 8 characters, a single line and only characters of the Base64 alphabet",
+		},
+		"html body": {
+			status:   502,
+			body:     `<html>Bad Gateway</html>`,
+			sentinel: nil,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+				_, _ = fmt.Fprint(w, test.body)
+			}))
+			defer server.Close()
+
+			_, err := New(server.URL, "token", http.DefaultClient, WithRetryPolicy(RetryPolicy{})).CreateVar(context.Background(), "a", CiVariable{Key: "KEY"})
+			var apiErr *ApiError
+			assert.True(t, errors.As(err, &apiErr))
+			assert.Equal(t, test.status, apiErr.StatusCode)
+			assert.Equal(t, http.MethodPost, apiErr.Method)
+			assert.Equal(t, test.message, apiErr.Message)
+			if test.sentinel != nil {
+				assert.ErrorIs(t, err, test.sentinel)
+			}
+			assert.Equal(t, test.hint, Hint(err))
+		})
+	}
+}
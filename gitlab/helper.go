@@ -1,9 +1,13 @@
 package gitlab
 
 import (
-	"fmt"
-	"github.com/dghubble/sling"
+	"context"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dghubble/sling"
 )
 
 type Query struct {
@@ -13,34 +17,135 @@ type Query struct {
 	PerPage int `url:"per_page,omitempty"`
 }
 
-func paginate[Type interface{}](req *sling.Sling, list []Type) ([]Type, error) {
-	query := &Query{
-		Page: 1,
+const (
+	// perPage is the maximum page size of the Gitlab API.
+	perPage = 100
+	// pageWorkers limits the number of pages fetched concurrently.
+	pageWorkers = 4
+)
+
+// paginate fetches all pages of a list. If Gitlab reports the total number of pages,
+// the remaining pages are fetched concurrently. Otherwise the X-Next-Page and Link
+// headers are followed.
+func paginate[Type interface{}](ctx context.Context, req *sling.Sling, list []Type) ([]Type, error) {
+	page, resp, err := fetchPage[Type](ctx, req.New().QueryStruct(&Query{Page: 1, PerPage: perPage}))
+	if err != nil {
+		return nil, err
 	}
-	req.QueryStruct(query)
-	for {
-		var page = make([]Type, 0)
-		var errorResponse ErrorResponse
-		resp, err := req.Receive(&page, &errorResponse)
-		err = handleHttpError(resp, err, errorResponse)
+	list = append(list, page...)
+	if total, err := strconv.Atoi(resp.Header.Get("X-Total-Pages")); err == nil {
+		if total < 2 {
+			return list, nil
+		}
+		pages, err := fetchPages[Type](ctx, req, 2, total)
 		if err != nil {
 			return nil, err
 		}
-		if len(page) == 0 {
-			break
+		return append(list, pages...), nil
+	}
+	for next := nextPage(req, resp, len(page)); next != nil; next = nextPage(req, resp, len(page)) {
+		page, resp, err = fetchPage[Type](ctx, next)
+		if err != nil {
+			return nil, err
 		}
 		list = append(list, page...)
-		query.Page++
 	}
 	return list, nil
 }
 
-func handleHttpError(response *http.Response, err error, errorResponse ErrorResponse) error {
+// nextPage returns the request for the page following resp or nil on the last page.
+func nextPage(req *sling.Sling, resp *http.Response, pageSize int) *sling.Sling {
+	if _, present := resp.Header["X-Next-Page"]; present {
+		next, err := strconv.Atoi(resp.Header.Get("X-Next-Page"))
+		if err != nil {
+			return nil
+		}
+		return req.New().QueryStruct(&Query{Page: next, PerPage: perPage})
+	}
+	if link := nextLink(resp.Header.Get("Link")); len(link) > 0 {
+		return req.New().Get(link)
+	}
+	if pageSize < perPage {
+		return nil
+	}
+	// without pagination headers, a full page may be followed by another one
+	current, _ := strconv.Atoi(resp.Request.URL.Query().Get("page"))
+	return req.New().QueryStruct(&Query{Page: current + 1, PerPage: perPage})
+}
+
+// nextLink extracts the rel="next" URL of a Link header.
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		if len(parts) < 2 {
+			continue
+		}
+		for _, param := range parts[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// fetchPages fetches the pages first to last with a bounded number of workers and keeps their order.
+func fetchPages[Type interface{}](ctx context.Context, req *sling.Sling, first int, last int) ([]Type, error) {
+	pages := make([][]Type, last-first+1)
+	errs := make([]error, last-first+1)
+	numbers := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < pageWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for number := range numbers {
+				pageReq := req.New().QueryStruct(&Query{Page: number, PerPage: perPage})
+				pages[number-first], _, errs[number-first] = fetchPage[Type](ctx, pageReq)
+			}
+		}()
+	}
+	for number := first; number <= last; number++ {
+		numbers <- number
+	}
+	close(numbers)
+	wg.Wait()
+
+	var list []Type
+	for i, page := range pages {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		list = append(list, page...)
+	}
+	return list, nil
+}
+
+func fetchPage[Type interface{}](ctx context.Context, req *sling.Sling) ([]Type, *http.Response, error) {
+	var page = make([]Type, 0)
+	var errorResponse ErrorResponse
+	resp, err := receive(ctx, req, &page, &errorResponse)
+	err = handleHttpError(resp, err, errorResponse)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	if response.StatusCode > 399 {
-		return fmt.Errorf("received status code [%d] on url: %s\nerror: %s\nmessage: %s", response.StatusCode, response.Request.URL, errorResponse.Error, errorResponse.Message)
+	return page, resp, nil
+}
+
+// receive sends the request bound to ctx, so it is aborted once ctx is done.
+func receive(ctx context.Context, req *sling.Sling, successV interface{}, failureV interface{}) (*http.Response, error) {
+	httpReq, err := req.Request()
+	if err != nil {
+		return nil, err
+	}
+	return req.Do(httpReq.WithContext(ctx), successV, failureV)
+}
+
+// handleHttpError turns responses with a status code above 399 into an ApiError,
+// even if their body could not be decoded.
+func handleHttpError(response *http.Response, err error, errorResponse ErrorResponse) error {
+	if response != nil && response.StatusCode > 399 {
+		return newApiError(response, errorResponse)
 	}
-	return nil
+	return err
 }
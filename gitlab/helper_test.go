@@ -0,0 +1,54 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaginate(t *testing.T) {
+	tests := map[string]func(w http.ResponseWriter, r *http.Request, page int, url string){
+		"total pages": func(w http.ResponseWriter, r *http.Request, page int, url string) {
+			w.Header().Set("X-Total-Pages", "3")
+		},
+		"next page header": func(w http.ResponseWriter, r *http.Request, page int, url string) {
+			next := ""
+			if page < 3 {
+				next = strconv.Itoa(page + 1)
+			}
+			w.Header().Set("X-Next-Page", next)
+		},
+		"link header": func(w http.ResponseWriter, r *http.Request, page int, url string) {
+			if page < 3 {
+				w.Header().Set("Link", fmt.Sprintf(`<%s/api/v4/projects/a%%2Fb/variables?page=%d&per_page=100>; rel="next", <%s>; rel="first"`, url, page+1, url))
+			}
+		},
+	}
+	for testName, setHeaders := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var requests int32
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				assert.Equal(t, "/api/v4/projects/a%2Fb/variables", r.URL.EscapedPath())
+				assert.Equal(t, "100", r.URL.Query().Get("per_page"))
+				page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+				setHeaders(w, r, page, server.URL)
+				_ = json.NewEncoder(w).Encode([]CiVariable{{Key: fmt.Sprintf("KEY%d", page)}})
+			}))
+			defer server.Close()
+
+			vars, err := New(server.URL, "token", http.DefaultClient).GetProjectVars(context.Background(), "a/b")
+			assert.NoError(t, err)
+			assert.Equal(t, CiVariableList{{Key: "KEY1"}, {Key: "KEY2"}, {Key: "KEY3"}}, vars)
+			assert.Equal(t, int32(3), requests)
+		})
+	}
+}
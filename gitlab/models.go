@@ -1,23 +1,57 @@
 package gitlab
 
+import "encoding/json"
+
+// CiVariable is a CI/CD variable of a project, group or instance.
+// Docs: https://docs.gitlab.com/ee/api/project_level_variables.html
 type CiVariable struct {
-	Key              string `json:"key"`
-	VariableType     string `json:"variable_type"`
-	Value            string `json:"value"`
-	Protected        bool   `json:"protected"`
-	Masked           bool   `json:"masked"`
+	Key          string `json:"key"`
+	VariableType string `json:"variable_type"`
+	// Value is empty for hidden variables, Gitlab never returns their value.
+	Value     string `json:"value"`
+	Protected bool   `json:"protected"`
+	Masked    bool   `json:"masked"`
+	// Hidden variables are masked and their value can not be revealed after creation.
+	Hidden bool `json:"hidden"`
+	// Raw variables are not expanded.
+	Raw         bool   `json:"raw"`
+	Description string `json:"description"`
+	// EnvironmentScope is always * for instance variables. Gitlab only respects the
+	// scope of group variables on Premium and Ultimate tiers.
 	EnvironmentScope string `json:"environment_scope"`
+	// Omitted are the attributes the input did not specify. Updates keep their current values.
+	// It is never sent to Gitlab.
+	Omitted Attributes `json:"-"`
 }
 
+// Attributes is a set of the attributes of a variable besides key, value and scope.
+type Attributes uint8
+
+const (
+	VariableTypeAttribute Attributes = 1 << iota
+	ProtectedAttribute
+	MaskedAttribute
+	RawAttribute
+	DescriptionAttribute
+
+	AllAttributes = VariableTypeAttribute | ProtectedAttribute | MaskedAttribute | RawAttribute | DescriptionAttribute
+)
+
 type CiVariableList []CiVariable
 
 func (c *CiVariableList) Includes(needle CiVariable) bool {
+	_, found := c.Find(needle)
+	return found
+}
+
+// Find returns the variable with the same key and environment scope as needle.
+func (c *CiVariableList) Find(needle CiVariable) (CiVariable, bool) {
 	for _, variable := range *c {
 		if variable.Key == needle.Key && variable.EnvironmentScope == needle.EnvironmentScope {
-			return true
+			return variable, true
 		}
 	}
-	return false
+	return CiVariable{}, false
 }
 func (c *CiVariableList) Push(newElement ...CiVariable) {
 	*c = append(*c, newElement...)
@@ -29,15 +63,50 @@ type Project struct {
 }
 
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
+	Error string `json:"error"`
+	// Message is either a text or an object of validation errors.
+	Message json.RawMessage `json:"message"`
+}
+
+// CreateBody adds create-only parameters to a variable.
+type CreateBody struct {
+	CiVariable
+	MaskedAndHidden bool `json:"masked_and_hidden,omitempty"`
 }
 
+func NewCreateBody(variable CiVariable) CreateBody {
+	return CreateBody{CiVariable: variable, MaskedAndHidden: variable.Hidden}
+}
+
+// UpdateBody carries all attributes of a variable, so an update replaces the stored variable.
 type UpdateBody struct {
-	Value  string `url:"value"`
-	Filter Filter `url:"filter"`
+	Value            string `url:"value"`
+	VariableType     string `url:"variable_type,omitempty"`
+	Protected        bool   `url:"protected"`
+	Masked           bool   `url:"masked"`
+	Raw              bool   `url:"raw"`
+	Description      string `url:"description"`
+	EnvironmentScope string `url:"environment_scope,omitempty"`
+	Filter           Filter `url:"filter"`
+}
+
+func NewUpdateBody(variable CiVariable) UpdateBody {
+	return UpdateBody{
+		Value:            variable.Value,
+		VariableType:     variable.VariableType,
+		Protected:        variable.Protected,
+		Masked:           variable.Masked,
+		Raw:              variable.Raw,
+		Description:      variable.Description,
+		EnvironmentScope: variable.EnvironmentScope,
+		Filter:           Filter{variable.EnvironmentScope},
+	}
 }
 
 type Filter struct {
 	EnvironmentScope string `url:"environment_scope"`
 }
+
+type DeleteQuery struct {
+	Filter Filter `url:"filter"`
+}
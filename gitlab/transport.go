@@ -0,0 +1,150 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how requests failing with rate limits or transient errors are retried.
+type RetryPolicy struct {
+	MaxRetries int
+	// BaseDelay is doubled on every retry unless Gitlab tells how long to wait.
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between two attempts.
+	MaxDelay time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 5,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   time.Minute,
+}
+
+// retryTransport retries idempotent requests on 429, 502, 503 and 504 responses and on network errors.
+// Non idempotent requests are only retried on 429, since Gitlab did not process them.
+type retryTransport struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+	// log receives a line per retry, nil disables the output
+	log   io.Writer
+	sleep func(ctx context.Context, d time.Duration) error
+	now   func() time.Time
+}
+
+func newRetryTransport(next http.RoundTripper, policy RetryPolicy, log io.Writer) *retryTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &retryTransport{next: next, policy: policy, log: log, sleep: sleep, now: time.Now}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		resp, err := t.next.RoundTrip(req)
+		if attempt >= t.policy.MaxRetries || !t.retryable(req, resp, err) {
+			return resp, err
+		}
+		delay := t.delay(resp, attempt)
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		if t.log != nil {
+			_, _ = fmt.Fprintf(t.log, "retry %d/%d %s %s in %s: %s\n", attempt+1, t.policy.MaxRetries, req.Method, req.URL, delay, reason)
+		}
+		if err := t.sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleep waits for d and returns early with the error of ctx if it is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *retryTransport) retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.GetBody == nil {
+		// the body can not be sent again
+		return false
+	}
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if !idempotent(req.Method) {
+		return false
+	}
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// delay honors the Retry-After and RateLimit-Reset headers and falls back to exponential backoff.
+func (t *retryTransport) delay(resp *http.Response, attempt int) time.Duration {
+	delay := t.policy.BaseDelay * time.Duration(math.Pow(2, float64(attempt)))
+	if resp != nil {
+		if wait, ok := t.headerDelay(resp.Header); ok {
+			delay = wait
+		}
+	}
+	if delay > t.policy.MaxDelay {
+		return t.policy.MaxDelay
+	}
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+func (t *retryTransport) headerDelay(header http.Header) (time.Duration, bool) {
+	if retryAfter := header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			return date.Sub(t.now()), true
+		}
+	}
+	if reset := header.Get("RateLimit-Reset"); reset != "" {
+		if timestamp, err := strconv.ParseInt(reset, 10, 64); err == nil {
+			return time.Unix(timestamp, 0).Sub(t.now()), true
+		}
+	}
+	return 0, false
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryTransport(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := map[string]struct {
+		method   string
+		statuses []int
+		header   http.Header
+		attempts int
+		delays   []time.Duration
+	}{
+		"retries get on bad gateway": {
+			method:   http.MethodGet,
+			statuses: []int{502, 503, 200},
+			attempts: 3,
+			delays:   []time.Duration{time.Second, 2 * time.Second},
+		},
+		"does not retry post on bad gateway": {
+			method:   http.MethodPost,
+			statuses: []int{502},
+			attempts: 1,
+		},
+		"retries post on rate limit with retry-after": {
+			method:   http.MethodPost,
+			statuses: []int{429, 201},
+			header:   http.Header{"Retry-After": []string{"3"}},
+			attempts: 2,
+			delays:   []time.Duration{3 * time.Second},
+		},
+		"waits until rate limit reset": {
+			method:   http.MethodPut,
+			statuses: []int{429, 200},
+			header:   http.Header{"Ratelimit-Reset": []string{strconv.FormatInt(now.Unix()+20, 10)}},
+			attempts: 2,
+			delays:   []time.Duration{20 * time.Second},
+		},
+		"gives up after max retries": {
+			method:   http.MethodDelete,
+			statuses: []int{504, 504, 504, 504},
+			attempts: 4,
+			delays:   []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				assert.Equal(t, "body", string(body))
+				for key, values := range test.header {
+					w.Header()[key] = values
+				}
+				w.WriteHeader(test.statuses[attempts])
+				attempts++
+			}))
+			defer server.Close()
+
+			var delays []time.Duration
+			var log bytes.Buffer
+			transport := newRetryTransport(nil, RetryPolicy{MaxRetries: 3, BaseDelay: time.Second, MaxDelay: time.Minute}, &log)
+			transport.sleep = func(ctx context.Context, d time.Duration) error {
+				delays = append(delays, d)
+				return nil
+			}
+			transport.now = func() time.Time { return now }
+
+			req, _ := http.NewRequest(test.method, server.URL, strings.NewReader("body"))
+			resp, err := transport.RoundTrip(req)
+			assert.NoError(t, err)
+			assert.Equal(t, test.statuses[len(test.statuses)-1], resp.StatusCode)
+			assert.Equal(t, test.attempts, attempts)
+			assert.Equal(t, test.delays, delays)
+			assert.Equal(t, len(test.delays), strings.Count(log.String(), "retry "))
+		})
+	}
+}
+
+func TestRetryTransportCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	transport := newRetryTransport(nil, RetryPolicy{MaxRetries: 3, BaseDelay: time.Minute, MaxDelay: time.Minute}, nil)
+	transport.sleep = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return sleep(ctx, d)
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	_, err := transport.RoundTrip(req)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,141 @@
+// Package civar manages Gitlab CI/CD variables from Go programs.
+//
+// It offers the operations of the civar command line tool with structured
+// results instead of printed output:
+//
+//	client := civar.NewClient("https://gitlab.com", os.Getenv("GITLAB_TOKEN"))
+//	vars, err := client.Get(ctx, civar.Project("group/project"), "")
+//	plan, err := client.Apply(ctx, civar.Project("group/project"), vars, civar.ApplyOptions{DryRun: true})
+package civar
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/ninogresenz/civar/gitlab"
+	"github.com/ninogresenz/civar/service"
+)
+
+type (
+	// Variable is a single CI/CD variable with all its attributes.
+	Variable = gitlab.CiVariable
+	// VariableList is a list of CI/CD variables of one or more environment scopes.
+	VariableList = gitlab.CiVariableList
+	// Target identifies the project, group or instance owning the variables.
+	Target = service.Target
+	// Plan lists the changes needed to bring a target in line with the desired variables.
+	Plan = service.Plan
+	// Change is a single update of a Plan.
+	Change = service.Change
+	// Diff lists the differences between two sets of variables per environment scope.
+	Diff = service.Diff
+	// KeyTransform rewrites the key of a variable, see ParseKeyTransforms.
+	KeyTransform = service.KeyTransform
+	// InterruptedError is returned by Apply and Import if ctx is done before all changes are made.
+	InterruptedError = service.InterruptedError
+)
+
+// Supported formats of Export and Import.
+const (
+	FormatJson      = "json"
+	FormatDotenv    = "dotenv"
+	FormatYaml      = "yaml"
+	FormatPretty    = "pretty"
+	FormatWide      = "wide"
+	FormatTerraform = "terraform"
+	// FormatKubernetes prints a Secret manifest, Export requires a scope if the target has several.
+	// Hidden variables are left out.
+	FormatKubernetes = "kubernetes"
+)
+
+var (
+	// ParseKeyTransforms reads transforms like add-prefix=APP_ or rename=^OLD_(.*)=NEW_$1.
+	ParseKeyTransforms = service.ParseKeyTransforms
+	// K8sExport strips the K8S_SECRET_ prefix from exported keys.
+	K8sExport = service.K8sExport
+)
+
+// Project returns the Target of the project with the given path or id.
+func Project(path string) Target {
+	return Target{Kind: service.ProjectTarget, Path: path}
+}
+
+// Group returns the Target of the group with the given path or id.
+func Group(path string) Target {
+	return Target{Kind: service.GroupTarget, Path: path}
+}
+
+// Instance returns the Target of the instance level variables.
+func Instance() Target {
+	return Target{Kind: service.InstanceTarget}
+}
+
+// ApplyOptions control how Apply and Import change the target.
+type ApplyOptions struct {
+	// Prune deletes variables of the target missing in the input
+	Prune bool
+	// DryRun only returns the plan without changing the target
+	DryRun bool
+	// K8s adds the K8S_SECRET_ prefix to all keys
+	K8s bool
+	// KeyTransforms rewrite all keys after the K8s prefix was added, see ParseKeyTransforms
+	KeyTransforms []string
+}
+
+// Client reads and changes CI/CD variables through the Gitlab API.
+type Client struct {
+	service service.Service
+}
+
+// NewClient creates a Client for the Gitlab instance at gitlabUrl.
+// Requests failing with rate limits or transient errors are retried.
+func NewClient(gitlabUrl string, token string, options ...gitlab.Option) *Client {
+	return NewClientWithApi(gitlab.New(gitlabUrl, token, &http.Client{}, options...))
+}
+
+// NewClientWithApi creates a Client using an existing gitlab.Api.
+func NewClientWithApi(api gitlab.Api) *Client {
+	return &Client{service.NewService(api)}
+}
+
+// Get returns the variables of the target. If scope is not empty, only the variables of this scope are returned.
+func (c *Client) Get(ctx context.Context, target Target, scope string) (VariableList, error) {
+	return c.service.Get(ctx, io.Discard, target, service.GetOptions{Format: FormatJson, Scope: scope})
+}
+
+// Apply creates missing and updates changed variables so the target matches vars.
+func (c *Client) Apply(ctx context.Context, target Target, vars VariableList, options ApplyOptions) (Plan, error) {
+	input := service.Input{Vars: inputVars(vars), K8s: options.K8s, KeyTransforms: options.KeyTransforms}
+	return c.service.Apply(ctx, io.Discard, target, input, options.Prune, options.DryRun)
+}
+
+// Diff compares the variables of the target with vars.
+func (c *Client) Diff(ctx context.Context, target Target, vars VariableList) (Diff, error) {
+	return c.service.Diff(ctx, io.Discard, target, service.Input{Vars: inputVars(vars)}, "text")
+}
+
+// Export returns the variables of the target in the given format with their keys rewritten by transforms.
+// Pass K8sExport()... to strip the K8S_SECRET_ prefix. If scope is not empty, only the variables of this scope are exported.
+func (c *Client) Export(ctx context.Context, target Target, format string, scope string, transforms ...KeyTransform) ([]byte, error) {
+	var out bytes.Buffer
+	if _, err := c.service.Get(ctx, &out, target, service.GetOptions{Format: format, Scope: scope, Transforms: transforms}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Import reads variables in the given format from r and applies them to the target.
+func (c *Client) Import(ctx context.Context, target Target, r io.Reader, format string, options ApplyOptions) (Plan, error) {
+	input := service.Input{Format: format, K8s: options.K8s, KeyTransforms: options.KeyTransforms, Stdin: r}
+	return c.service.Apply(ctx, io.Discard, target, input, options.Prune, options.DryRun)
+}
+
+// inputVars makes sure an empty list is not mistaken for missing input.
+func inputVars(vars VariableList) VariableList {
+	if vars == nil {
+		return VariableList{}
+	}
+	return vars
+}
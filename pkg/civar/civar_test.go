@@ -0,0 +1,79 @@
+package civar_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ninogresenz/civar/pkg/civar"
+)
+
+func TestClient(t *testing.T) {
+	var mu sync.Mutex
+	vars := civar.VariableList{
+		{Key: "KEEP", VariableType: "env_var", Value: "1", EnvironmentScope: "*"},
+		{Key: "CHANGE", VariableType: "env_var", Value: "1", EnvironmentScope: "*"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.True(t, strings.HasPrefix(r.URL.EscapedPath(), "/api/v4/projects/a%2Fb/variables"))
+		switch r.Method {
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode(vars)
+		case http.MethodPost:
+			var variable civar.Variable
+			_ = json.NewDecoder(r.Body).Decode(&variable)
+			vars.Push(variable)
+			_ = json.NewEncoder(w).Encode(variable)
+		case http.MethodPut:
+			_ = r.ParseForm()
+			vars[1].Value = r.Form.Get("value")
+			_ = json.NewEncoder(w).Encode(vars[1])
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := civar.NewClient(server.URL, "token")
+	target := civar.Project("a/b")
+
+	got, err := client.Get(ctx, target, "")
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+
+	exported, err := client.Export(ctx, target, civar.FormatDotenv, "")
+	assert.NoError(t, err)
+	assert.Contains(t, string(exported), "KEEP=1")
+
+	desired := civar.VariableList{
+		{Key: "KEEP", VariableType: "env_var", Value: "1", EnvironmentScope: "*"},
+		{Key: "CHANGE", VariableType: "env_var", Value: "2", EnvironmentScope: "*"},
+		{Key: "ADD", VariableType: "env_var", Value: "3", EnvironmentScope: "*"},
+	}
+	diff, err := client.Diff(ctx, target, desired)
+	assert.NoError(t, err)
+	assert.True(t, diff.HasChanges())
+
+	plan, err := client.Apply(ctx, target, desired, civar.ApplyOptions{DryRun: true})
+	assert.NoError(t, err)
+	assert.Len(t, plan.Creates, 1)
+	assert.Len(t, plan.Updates, 1)
+	assert.Len(t, vars, 2)
+
+	plan, err = client.Import(ctx, target, bytes.NewBufferString("KEEP=1\nCHANGE=2\nADD=3\n"), civar.FormatDotenv, civar.ApplyOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, plan.Creates, 1)
+	assert.Equal(t, civar.VariableList{
+		{Key: "KEEP", VariableType: "env_var", Value: "1", EnvironmentScope: "*"},
+		{Key: "CHANGE", VariableType: "env_var", Value: "2", EnvironmentScope: "*"},
+		{Key: "ADD", VariableType: "env_var", Value: "3", EnvironmentScope: "*"},
+	}, vars)
+}
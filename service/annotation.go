@@ -0,0 +1,98 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ninogresenz/civar/gitlab"
+)
+
+// Annotations are written as a comment above a dotenv key to keep the attributes
+// of a variable, e.g. "# @masked @protected @file". A description is quoted and
+// always comes last: "# @raw @description="Used by the deploy job"".
+// The negated forms like @unprotected state that an attribute is off.
+const (
+	AnnotationPrefix = "# @"
+
+	maskedAnnotation      = "@masked"
+	unmaskedAnnotation    = "@unmasked"
+	protectedAnnotation   = "@protected"
+	unprotectedAnnotation = "@unprotected"
+	fileAnnotation        = "@file"
+	envAnnotation         = "@env"
+	rawAnnotation         = "@raw"
+	expandedAnnotation    = "@expanded"
+	hiddenAnnotation      = "@hidden"
+	descriptionAnnotation = "@description="
+
+	fileVariableType = "file"
+	envVariableType  = "env_var"
+)
+
+// annotationOf renders all attributes of a variable, so the annotation replaces
+// the attributes of an existing variable when it is applied.
+func annotationOf(variable gitlab.CiVariable) string {
+	annotations := []string{unmaskedAnnotation, unprotectedAnnotation, envAnnotation, expandedAnnotation}
+	if variable.Hidden {
+		annotations[0] = hiddenAnnotation
+	} else if variable.Masked {
+		annotations[0] = maskedAnnotation
+	}
+	if variable.Protected {
+		annotations[1] = protectedAnnotation
+	}
+	if variable.VariableType == fileVariableType {
+		annotations[2] = fileAnnotation
+	}
+	if variable.Raw {
+		annotations[3] = rawAnnotation
+	}
+	annotations = append(annotations, descriptionAnnotation+strconv.Quote(variable.Description))
+	return "# " + strings.Join(annotations, " ")
+}
+
+// applyAnnotation sets the attributes of an annotation comment on the variable and
+// marks them as given by the input. Unknown annotations are ignored.
+func applyAnnotation(annotation string, variable *gitlab.CiVariable) {
+	annotation, description, found := strings.Cut(annotation, descriptionAnnotation)
+	if found {
+		unquoted, err := strconv.Unquote(strings.TrimSpace(description))
+		if err != nil {
+			unquoted = strings.TrimSpace(description)
+		}
+		variable.Description = unquoted
+		variable.Omitted &^= gitlab.DescriptionAttribute
+	}
+	for _, field := range strings.Fields(strings.TrimPrefix(annotation, "#")) {
+		switch field {
+		case hiddenAnnotation:
+			variable.Hidden = true
+			variable.Masked = true
+			variable.Omitted &^= gitlab.MaskedAttribute
+		case rawAnnotation, expandedAnnotation:
+			variable.Raw = field == rawAnnotation
+			variable.Omitted &^= gitlab.RawAttribute
+		case maskedAnnotation, unmaskedAnnotation:
+			variable.Masked = field == maskedAnnotation
+			variable.Omitted &^= gitlab.MaskedAttribute
+		case protectedAnnotation, unprotectedAnnotation:
+			variable.Protected = field == protectedAnnotation
+			variable.Omitted &^= gitlab.ProtectedAttribute
+		case fileAnnotation:
+			variable.VariableType = fileVariableType
+			variable.Omitted &^= gitlab.VariableTypeAttribute
+		case envAnnotation:
+			variable.VariableType = envVariableType
+			variable.Omitted &^= gitlab.VariableTypeAttribute
+		}
+	}
+}
+
+// dotenvKey returns the key of a dotenv line like `export KEY="value"`.
+func dotenvKey(line string) string {
+	line = strings.TrimPrefix(strings.TrimSpace(line), "export ")
+	if i := strings.IndexAny(line, "=:"); i >= 0 {
+		line = line[:i]
+	}
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ninogresenz/civar/gitlab"
+)
+
+// ApplySettings returns a copy of the variables with the attributes given as name=value pairs,
+// e.g. "protected=true". Supported names are protected, masked, raw, variable_type and description.
+func ApplySettings(data gitlab.CiVariableList, settings []string) (gitlab.CiVariableList, error) {
+	updated := make(gitlab.CiVariableList, len(data))
+	copy(updated, data)
+	for _, setting := range settings {
+		name, value, found := strings.Cut(setting, "=")
+		if !found {
+			return nil, fmt.Errorf("setting must look like name=value: %s", setting)
+		}
+		set, err := setter(strings.TrimSpace(name), strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		for i := range updated {
+			set(&updated[i])
+		}
+	}
+	return updated, nil
+}
+
+// attributeNames maps the json and yaml names of the attributes an input may omit.
+var attributeNames = map[string]gitlab.Attributes{
+	"variable_type": gitlab.VariableTypeAttribute,
+	"protected":     gitlab.ProtectedAttribute,
+	"masked":        gitlab.MaskedAttribute,
+	"raw":           gitlab.RawAttribute,
+	"description":   gitlab.DescriptionAttribute,
+}
+
+// omittedAttributes returns the attributes missing in the given field names.
+func omittedAttributes(names []string) gitlab.Attributes {
+	omitted := gitlab.AllAttributes
+	for _, name := range names {
+		omitted &^= attributeNames[name]
+	}
+	return omitted
+}
+
+// keepAttributes returns desired with the attributes it omits taken from current.
+// Hidden is always taken from current, since it can not be changed.
+func keepAttributes(current gitlab.CiVariable, desired gitlab.CiVariable) gitlab.CiVariable {
+	if desired.Omitted&gitlab.VariableTypeAttribute != 0 {
+		desired.VariableType = current.VariableType
+	}
+	if desired.Omitted&gitlab.ProtectedAttribute != 0 {
+		desired.Protected = current.Protected
+	}
+	if desired.Omitted&gitlab.MaskedAttribute != 0 {
+		desired.Masked = current.Masked
+	}
+	if desired.Omitted&gitlab.RawAttribute != 0 {
+		desired.Raw = current.Raw
+	}
+	if desired.Omitted&gitlab.DescriptionAttribute != 0 {
+		desired.Description = current.Description
+	}
+	desired.Hidden = current.Hidden
+	desired.Omitted = 0
+	return desired
+}
+
+func setter(name string, value string) (func(variable *gitlab.CiVariable), error) {
+	switch name {
+	case "protected", "masked", "raw":
+		flag, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false: %s", name, value)
+		}
+		switch name {
+		case "protected":
+			return func(variable *gitlab.CiVariable) { variable.Protected = flag }, nil
+		case "masked":
+			return func(variable *gitlab.CiVariable) { variable.Masked = flag }, nil
+		default:
+			return func(variable *gitlab.CiVariable) { variable.Raw = flag }, nil
+		}
+	case "description":
+		return func(variable *gitlab.CiVariable) { variable.Description = value }, nil
+	case "variable_type":
+		if value != envVariableType && value != fileVariableType {
+			return nil, fmt.Errorf("variable_type must be one of [%s | %s]: %s", envVariableType, fileVariableType, value)
+		}
+		return func(variable *gitlab.CiVariable) { variable.VariableType = value }, nil
+	default:
+		return nil, fmt.Errorf("unknown attribute: %s", name)
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"io"
+
+	"github.com/ninogresenz/civar/gitlab"
+)
+
+// CopyOptions control which variables Copy transfers and how.
+type CopyOptions struct {
+	// Scope copies only the variables of this environment scope if set
+	Scope string
+	// Overwrite updates variables which already exist in the destination instead of skipping them
+	Overwrite bool
+	DryRun    bool
+	// ScopeMappings and ScopeMappingFile rewrite the environment scopes like in Input
+	ScopeMappings    []string
+	ScopeMappingFile string
+	// KeyTransforms rewrite the keys like in Input
+	KeyTransforms []string
+}
+
+// Copy transfers the variables of from to the target to with all their attributes.
+// The services may talk to different Gitlab instances. Hidden variables end up in Plan.Hidden.
+func Copy(ctx context.Context, w io.Writer, source Service, from Target, destination Service, to Target, options CopyOptions) (Plan, error) {
+	data, err := source.Get(ctx, io.Discard, from, GetOptions{Format: jsonFormat, Scope: options.Scope})
+	if err != nil {
+		return Plan{}, err
+	}
+	if data == nil {
+		data = gitlab.CiVariableList{}
+	}
+	input := Input{
+		Format:           jsonFormat,
+		Vars:             data,
+		ScopeMappings:    options.ScopeMappings,
+		ScopeMappingFile: options.ScopeMappingFile,
+		KeyTransforms:    options.KeyTransforms,
+	}
+	if options.Overwrite {
+		return destination.Apply(ctx, w, to, input, false, options.DryRun)
+	}
+	return destination.Create(ctx, w, to, input, options.DryRun)
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ninogresenz/civar/gitlab"
+)
+
+const (
+	textDiffFormat = "text"
+	jsonDiffFormat = "json"
+)
+
+// ScopeDiff lists the differences of one environment scope.
+type ScopeDiff struct {
+	Scope   string                `json:"scope"`
+	Added   gitlab.CiVariableList `json:"added"`
+	Removed gitlab.CiVariableList `json:"removed"`
+	Changed []Change              `json:"changed"`
+}
+
+// Diff lists the differences between two sets of variables grouped by environment scope.
+type Diff []ScopeDiff
+
+// NewDiff compares two sets of variables by key and environment scope.
+// Added variables only exist in to, removed variables only exist in from.
+// Unlike a plan, attributes omitted by to are compared as parsed instead of being kept from from.
+func NewDiff(from gitlab.CiVariableList, to gitlab.CiVariableList) Diff {
+	scopes := make(map[string]*ScopeDiff)
+	get := func(scope string) *ScopeDiff {
+		if _, present := scopes[scope]; !present {
+			scopes[scope] = &ScopeDiff{
+				Scope:   scope,
+				Added:   gitlab.CiVariableList{},
+				Removed: gitlab.CiVariableList{},
+				Changed: []Change{},
+			}
+		}
+		return scopes[scope]
+	}
+	for _, variable := range to {
+		current, found := from.Find(variable)
+		variable.Omitted = 0
+		switch {
+		case !found:
+			get(variable.EnvironmentScope).Added.Push(variable)
+		case !Equal(current, variable):
+			scopeDiff := get(variable.EnvironmentScope)
+			scopeDiff.Changed = append(scopeDiff.Changed, Change{Old: current, New: variable})
+		}
+	}
+	for _, variable := range from {
+		if !to.Includes(variable) {
+			get(variable.EnvironmentScope).Removed.Push(variable)
+		}
+	}
+
+	diff := Diff{}
+	for _, scopeDiff := range scopes {
+		sortByKey(scopeDiff.Added)
+		sortByKey(scopeDiff.Removed)
+		sort.SliceStable(scopeDiff.Changed, func(i, j int) bool {
+			return scopeDiff.Changed[i].New.Key < scopeDiff.Changed[j].New.Key
+		})
+		diff = append(diff, *scopeDiff)
+	}
+	sort.Slice(diff, func(i, j int) bool {
+		return scopeLess(diff[i].Scope, diff[j].Scope)
+	})
+	return diff
+}
+
+// HasChanges reports whether both sides differ.
+func (d Diff) HasChanges() bool {
+	return len(d) > 0
+}
+
+// Print renders the diff as unified text or json. Values of masked variables are redacted.
+func (d Diff) Print(format string, fromName string, toName string) (string, error) {
+	switch format {
+	case jsonDiffFormat:
+		prettyJson, err := json.MarshalIndent(d.redacted(), "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(prettyJson), nil
+	case textDiffFormat:
+		return d.text(fromName, toName), nil
+	default:
+		return "", fmt.Errorf("not a valid diff format: %s", format)
+	}
+}
+
+// text renders the diff similar to a unified diff. Values of masked variables are redacted.
+func (d Diff) text(fromName string, toName string) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", fromName, toName))
+	for _, scopeDiff := range d {
+		b.WriteString(fmt.Sprintf("@@ %s @@\n", scopeDiff.Scope))
+		for _, variable := range scopeDiff.Removed {
+			b.WriteString(fmt.Sprintf("- %s=%s\n", variable.Key, planValue(variable, variable)))
+		}
+		for _, variable := range scopeDiff.Added {
+			b.WriteString(fmt.Sprintf("+ %s=%s\n", variable.Key, planValue(variable, variable)))
+		}
+		for _, change := range scopeDiff.Changed {
+			b.WriteString(fmt.Sprintf("~ %s\n", change.New.Key))
+			for _, field := range change.Fields() {
+				b.WriteString(fmt.Sprintf("-   %s: %s\n+   %s: %s\n", field.Name, field.Old, field.Name, field.New))
+			}
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// redacted returns a copy of the diff with the values of masked variables replaced like in the text output.
+func (d Diff) redacted() Diff {
+	redacted := make(Diff, len(d))
+	for i, scopeDiff := range d {
+		redacted[i] = ScopeDiff{
+			Scope:   scopeDiff.Scope,
+			Added:   redactValues(scopeDiff.Added),
+			Removed: redactValues(scopeDiff.Removed),
+			Changed: make([]Change, len(scopeDiff.Changed)),
+		}
+		for j, change := range scopeDiff.Changed {
+			redacted[i].Changed[j] = Change{Old: redactValue(change.Old, change.New), New: redactValue(change.New, change.Old)}
+		}
+	}
+	return redacted
+}
+
+func redactValues(data gitlab.CiVariableList) gitlab.CiVariableList {
+	redacted := make(gitlab.CiVariableList, len(data))
+	for i, variable := range data {
+		redacted[i] = redactValue(variable, variable)
+	}
+	return redacted
+}
+
+func redactValue(variable gitlab.CiVariable, other gitlab.CiVariable) gitlab.CiVariable {
+	if placeholder, redacted := redaction(variable, other); redacted {
+		variable.Value = placeholder
+	}
+	return variable
+}
+
+func sortByKey(data gitlab.CiVariableList) {
+	sort.SliceStable(data, func(i, j int) bool {
+		return data[i].Key < data[j].Key
+	})
+}
+
+// scopeLess orders the * scope first and all other scopes alphabetically.
+func scopeLess(a string, b string) bool {
+	if a == AllScope || b == AllScope {
+		return a == AllScope && b != AllScope
+	}
+	return a < b
+}
@@ -0,0 +1,61 @@
+package service_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ninogresenz/civar/gitlab"
+	"github.com/ninogresenz/civar/service"
+)
+
+func TestDiff(t *testing.T) {
+	from := gitlab.CiVariableList{
+		{Key: "SAME", Value: "1", VariableType: "env_var", EnvironmentScope: "*"},
+		{Key: "REMOVED", Value: "1", VariableType: "env_var", EnvironmentScope: "staging"},
+		{Key: "CHANGED", Value: "1", VariableType: "env_var", EnvironmentScope: "production"},
+		{Key: "SECRET", Value: "1", VariableType: "env_var", EnvironmentScope: "production", Masked: true},
+	}
+	to := gitlab.CiVariableList{
+		{Key: "SAME", Value: "1", VariableType: "env_var", EnvironmentScope: "*"},
+		{Key: "ADDED", Value: "1", VariableType: "env_var", EnvironmentScope: "*"},
+		{Key: "CHANGED", Value: "2", VariableType: "file", EnvironmentScope: "production"},
+		{Key: "SECRET", Value: "2", VariableType: "env_var", EnvironmentScope: "production", Masked: true, Protected: true},
+	}
+
+	diff := service.NewDiff(from, to)
+	assert.True(t, diff.HasChanges())
+	output, err := diff.Print("text", "project a", ".env")
+	assert.NoError(t, err)
+	assert.Equal(t, `--- project a
++++ .env
+@@ * @@
++ ADDED="1"
+@@ production @@
+~ CHANGED
+-   value: "1"
++   value: "2"
+-   variable_type: env_var
++   variable_type: file
+~ SECRET
+-   value: (masked)
++   value: (masked)
+-   protected: false
++   protected: true
+@@ staging @@
+- REMOVED="1"`, output)
+
+	output, err = diff.Print("json", "project a", ".env")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(output, `"value": "(masked)"`))
+	assert.Equal(t, "2", diff[1].Changed[1].New.Value)
+
+	assert.False(t, service.NewDiff(from, from).HasChanges())
+
+	parsed, err := service.ParseDotEnv([]byte("# Scope: production\nSECRET=1\n"))
+	assert.NoError(t, err)
+	diff = service.NewDiff(from[3:], parsed)
+	assert.Len(t, diff, 1)
+	assert.Equal(t, []service.FieldChange{{Name: "masked", Old: "true", New: "false"}}, diff[0].Changed[0].Fields())
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ninogresenz/civar/gitlab"
+)
+
+// KubernetesOptions configure the manifests of the kubernetes format.
+type KubernetesOptions struct {
+	// Name of the Secret and ConfigMap, derived from the target if empty
+	Name      string
+	Namespace string
+	Labels    map[string]string
+	// ConfigMap moves values which are neither masked nor hidden from the Secret into a ConfigMap
+	ConfigMap bool
+}
+
+// KubernetesPrinter prints the variables of a single scope as a Secret manifest
+// with base64 encoded data and optionally a ConfigMap for the unmasked values.
+// Hidden variables are left out.
+type kubernetesPrinter struct {
+	target  Target
+	options KubernetesOptions
+}
+
+type kubernetesMetadata struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+type kubernetesManifest struct {
+	ApiVersion string             `yaml:"apiVersion"`
+	Kind       string             `yaml:"kind"`
+	Metadata   kubernetesMetadata `yaml:"metadata"`
+	Type       string             `yaml:"type,omitempty"`
+	Data       *yaml.Node         `yaml:"data"`
+}
+
+func (p kubernetesPrinter) Print(data gitlab.CiVariableList) (string, error) {
+	scopes := map[string]bool{}
+	for _, variable := range data {
+		scopes[variable.EnvironmentScope] = true
+	}
+	if len(scopes) > 1 {
+		return "", fmt.Errorf("the %s format prints a single scope, select one of %d scopes with a scope filter", kubernetesFormat, len(scopes))
+	}
+	data = append(gitlab.CiVariableList{}, data...)
+	sortByKey(data)
+
+	secret := &yaml.Node{Kind: yaml.MappingNode}
+	config := &yaml.Node{Kind: yaml.MappingNode}
+	for _, variable := range data {
+		if variable.Hidden {
+			continue
+		}
+		if p.options.ConfigMap && !variable.Masked && !variable.Hidden {
+			config.Content = append(config.Content, yamlString(variable.Key), yamlString(variable.Value))
+			continue
+		}
+		encoded := base64.StdEncoding.EncodeToString([]byte(variable.Value))
+		secret.Content = append(secret.Content, yamlString(variable.Key), yamlString(encoded))
+	}
+
+	metadata := kubernetesMetadata{Name: p.name(), Namespace: p.options.Namespace, Labels: p.options.Labels}
+	manifests := []kubernetesManifest{{ApiVersion: "v1", Kind: "Secret", Metadata: metadata, Type: "Opaque", Data: secret}}
+	if p.options.ConfigMap {
+		manifests = append(manifests, kubernetesManifest{ApiVersion: "v1", Kind: "ConfigMap", Metadata: metadata, Data: config})
+	}
+
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	for _, manifest := range manifests {
+		if err := encoder.Encode(manifest); err != nil {
+			return "", fmt.Errorf("could not marshal %s manifest: %w", manifest.Kind, err)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// name returns the configured name or derives a valid resource name from the target path.
+func (p kubernetesPrinter) name() string {
+	if len(p.options.Name) > 0 {
+		return p.options.Name
+	}
+	path := p.target.Path
+	if index := strings.LastIndex(path, "/"); index >= 0 {
+		path = path[index+1:]
+	}
+	name := strings.Trim(strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		default:
+			return '-'
+		}
+	}, path), "-.")
+	if len(name) == 0 {
+		return "ci-variables"
+	}
+	return name
+}
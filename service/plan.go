@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ninogresenz/civar/gitlab"
+)
+
+// Plan lists the operations needed to turn existing variables into the desired ones.
+type Plan struct {
+	Creates   gitlab.CiVariableList
+	Updates   []Change
+	Deletes   gitlab.CiVariableList
+	Unchanged gitlab.CiVariableList
+	// Skips are input variables the operation does not apply to
+	Skips gitlab.CiVariableList
+	// Hidden are variables without a value to write, see skipHidden
+	Hidden gitlab.CiVariableList
+}
+
+// Change holds the current and the desired state of an existing variable.
+type Change struct {
+	Old gitlab.CiVariable `json:"old"`
+	New gitlab.CiVariable `json:"new"`
+}
+
+// NewPlan compares the desired variables with the existing ones by key and environment scope.
+// Attributes omitted by desired keep their current values, new variables get the defaults.
+// Existing variables missing in desired are only deleted if prune is set.
+func NewPlan(existing gitlab.CiVariableList, desired gitlab.CiVariableList, prune bool) Plan {
+	plan := Plan{}
+	for _, variable := range desired {
+		current, found := existing.Find(variable)
+		if !found {
+			variable.Omitted = 0
+			plan.Creates.Push(variable)
+			continue
+		}
+		variable = keepAttributes(current, variable)
+		if Equal(current, variable) {
+			plan.Unchanged.Push(variable)
+			continue
+		}
+		plan.Updates = append(plan.Updates, Change{Old: current, New: variable})
+	}
+	if prune {
+		for _, variable := range existing {
+			if !desired.Includes(variable) {
+				plan.Deletes.Push(variable)
+			}
+		}
+	}
+	return plan
+}
+
+// createOnly skips all variables which already exist.
+func (p Plan) createOnly() Plan {
+	skips := append(gitlab.CiVariableList{}, p.Unchanged...)
+	for _, change := range p.Updates {
+		skips.Push(change.New)
+	}
+	return Plan{Creates: p.Creates, Skips: skips}
+}
+
+// updateOnly skips all variables which do not exist yet.
+func (p Plan) updateOnly() Plan {
+	return Plan{Updates: p.Updates, Unchanged: p.Unchanged, Skips: p.Creates}
+}
+
+// skipHidden moves creates and updates without a value for a hidden variable to Hidden.
+func (p Plan) skipHidden() Plan {
+	skipped := Plan{Deletes: p.Deletes, Unchanged: p.Unchanged, Skips: p.Skips, Hidden: p.Hidden}
+	for _, variable := range p.Creates {
+		if unknownValue(variable) {
+			skipped.Hidden.Push(variable)
+			continue
+		}
+		skipped.Creates.Push(variable)
+	}
+	for _, change := range p.Updates {
+		if unknownValue(change.New) {
+			skipped.Hidden.Push(change.New)
+			continue
+		}
+		skipped.Updates = append(skipped.Updates, change)
+	}
+	return skipped
+}
+
+// unknownValue reports whether the variable is hidden and has no value. Gitlab never returns
+// the value of a hidden variable, so it is empty when read from Gitlab or an exported file.
+// Writing it would clear the secret.
+func unknownValue(variable gitlab.CiVariable) bool {
+	return variable.Hidden && len(variable.Value) == 0
+}
+
+// HasChanges reports whether executing the plan would modify any variable.
+func (p Plan) HasChanges() bool {
+	return len(p.Creates) > 0 || len(p.Updates) > 0 || len(p.Deletes) > 0
+}
+
+// String renders the plan for humans. Values of masked variables are redacted.
+func (p Plan) String() string {
+	var b strings.Builder
+	for _, variable := range p.Creates {
+		b.WriteString(fmt.Sprintf("+ %s = %s\n", planKey(variable), planValue(variable, variable)))
+	}
+	for _, change := range p.Updates {
+		b.WriteString(fmt.Sprintf("~ %s\n", planKey(change.New)))
+		for _, field := range change.Fields() {
+			b.WriteString(fmt.Sprintf("    %s: %s -> %s\n", field.Name, field.Old, field.New))
+		}
+	}
+	for _, variable := range p.Deletes {
+		b.WriteString(fmt.Sprintf("- %s\n", planKey(variable)))
+	}
+	for _, variable := range p.Skips {
+		b.WriteString(fmt.Sprintf("  %s (skipped)\n", planKey(variable)))
+	}
+	for _, variable := range p.Hidden {
+		b.WriteString(fmt.Sprintf("  %s (skipped, hidden value unknown)\n", planKey(variable)))
+	}
+	b.WriteString(fmt.Sprintf("Plan: %d to create, %d to update, %d to delete, %d unchanged, %d skipped.",
+		len(p.Creates), len(p.Updates), len(p.Deletes), len(p.Unchanged), len(p.Skips)+len(p.Hidden)))
+	return b.String()
+}
+
+// FieldChange describes a changed attribute with both values rendered as text.
+type FieldChange struct {
+	Name string
+	Old  string
+	New  string
+}
+
+// Fields lists the changed attributes. Values of masked variables are redacted.
+func (c Change) Fields() []FieldChange {
+	var fields []FieldChange
+	if !valueEqual(c.Old, c.New) {
+		fields = append(fields, FieldChange{"value", planValue(c.Old, c.New), planValue(c.New, c.Old)})
+	}
+	if c.Old.VariableType != c.New.VariableType {
+		fields = append(fields, FieldChange{"variable_type", c.Old.VariableType, c.New.VariableType})
+	}
+	if c.Old.Protected != c.New.Protected {
+		fields = append(fields, FieldChange{"protected", strconv.FormatBool(c.Old.Protected), strconv.FormatBool(c.New.Protected)})
+	}
+	if c.Old.Masked != c.New.Masked {
+		fields = append(fields, FieldChange{"masked", strconv.FormatBool(c.Old.Masked), strconv.FormatBool(c.New.Masked)})
+	}
+	if c.Old.Raw != c.New.Raw {
+		fields = append(fields, FieldChange{"raw", strconv.FormatBool(c.Old.Raw), strconv.FormatBool(c.New.Raw)})
+	}
+	if c.Old.Description != c.New.Description {
+		fields = append(fields, FieldChange{"description", strconv.Quote(c.Old.Description), strconv.Quote(c.New.Description)})
+	}
+	return fields
+}
+
+func planKey(variable gitlab.CiVariable) string {
+	return fmt.Sprintf("[%s] %s", variable.EnvironmentScope, variable.Key)
+}
+
+// planValue quotes the value of a variable unless it or its counterpart is masked.
+func planValue(variable gitlab.CiVariable, other gitlab.CiVariable) string {
+	if placeholder, redacted := redaction(variable, other); redacted {
+		return placeholder
+	}
+	return strconv.Quote(variable.Value)
+}
+
+// redaction returns the placeholder shown instead of the value if the variable or its counterpart is masked.
+func redaction(variable gitlab.CiVariable, other gitlab.CiVariable) (string, bool) {
+	if variable.Hidden || other.Hidden {
+		return "(hidden)", true
+	}
+	if variable.Masked || other.Masked {
+		return "(masked)", true
+	}
+	return "", false
+}
+
+// Equal reports whether two variables have the same value and attributes.
+// Hidden is not compared since it can not be changed after creation.
+func Equal(a gitlab.CiVariable, b gitlab.CiVariable) bool {
+	return valueEqual(a, b) &&
+		a.VariableType == b.VariableType &&
+		a.Protected == b.Protected &&
+		a.Masked == b.Masked &&
+		a.Raw == b.Raw &&
+		a.Description == b.Description
+}
+
+// valueEqual compares the values of two variables. The unknown value of a hidden
+// variable equals an empty value, as exported by civar.
+func valueEqual(a gitlab.CiVariable, b gitlab.CiVariable) bool {
+	if (a.Hidden && len(b.Value) == 0) || (b.Hidden && len(a.Value) == 0) {
+		return true
+	}
+	return a.Value == b.Value
+}
+
+// execute runs the plan and stops at the first failure.
+// The context is checked before every request. If it is done before the plan
+// is complete, an *InterruptedError reports the completed and pending operations.
+func (s *service) execute(ctx context.Context, target Target, plan Plan) error {
+	done := Plan{}
+	for _, variable := range plan.Creates {
+		if err := ctx.Err(); err != nil {
+			return interrupted(plan, done, false, err)
+		}
+		_, err := s.createVar(ctx, target, variable)
+		if ctx.Err() != nil {
+			return interrupted(plan, done, true, ctx.Err())
+		}
+		if err != nil {
+			return fmt.Errorf("could not create variable [key: %s, scope:%s]: %w", variable.Key, variable.EnvironmentScope, err)
+		}
+		done.Creates.Push(variable)
+	}
+	for _, change := range plan.Updates {
+		if err := ctx.Err(); err != nil {
+			return interrupted(plan, done, false, err)
+		}
+		_, err := s.updateVar(ctx, target, change.New)
+		if ctx.Err() != nil {
+			return interrupted(plan, done, true, ctx.Err())
+		}
+		if err != nil {
+			return fmt.Errorf("could not update variable [key: %s, scope:%s]: %w", change.New.Key, change.New.EnvironmentScope, err)
+		}
+		done.Updates = append(done.Updates, change)
+	}
+	for _, variable := range plan.Deletes {
+		if err := ctx.Err(); err != nil {
+			return interrupted(plan, done, false, err)
+		}
+		err := s.deleteVar(ctx, target, variable)
+		if ctx.Err() != nil {
+			return interrupted(plan, done, true, ctx.Err())
+		}
+		if err != nil {
+			return fmt.Errorf("could not delete variable [key: %s, scope:%s]: %w", variable.Key, variable.EnvironmentScope, err)
+		}
+		done.Deletes.Push(variable)
+	}
+	return nil
+}
+
+// InterruptedError is returned if the context is done before a plan is fully executed.
+type InterruptedError struct {
+	Completed Plan
+	Pending   Plan
+	// InFlight reports whether the first pending operation was sent and may have been applied
+	InFlight bool
+	Err      error
+}
+
+// interrupted splits the plan into the done operations and the pending rest.
+func interrupted(plan Plan, done Plan, inFlight bool, err error) error {
+	return &InterruptedError{
+		Completed: done,
+		Pending: Plan{
+			Creates: plan.Creates[len(done.Creates):],
+			Updates: plan.Updates[len(done.Updates):],
+			Deletes: plan.Deletes[len(done.Deletes):],
+		},
+		InFlight: inFlight,
+		Err:      err,
+	}
+}
+
+func (e *InterruptedError) Error() string {
+	return fmt.Sprintf("interrupted after %d of %d operations: %s", e.Completed.operations(), e.Completed.operations()+e.Pending.operations(), e.Err)
+}
+
+func (e *InterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// Summary lists the completed and the pending operations.
+func (e *InterruptedError) Summary() string {
+	var b strings.Builder
+	b.WriteString("Completed:\n")
+	b.WriteString(e.Completed.operationList(false))
+	b.WriteString("Pending:\n")
+	b.WriteString(e.Pending.operationList(e.InFlight))
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func (p Plan) operations() int {
+	return len(p.Creates) + len(p.Updates) + len(p.Deletes)
+}
+
+// operationList renders one line per operation in execution order.
+// With inFlight, the first operation is marked as possibly applied.
+func (p Plan) operationList(inFlight bool) string {
+	var lines []string
+	for _, variable := range p.Creates {
+		lines = append(lines, fmt.Sprintf("+ %s", planKey(variable)))
+	}
+	for _, change := range p.Updates {
+		lines = append(lines, fmt.Sprintf("~ %s", planKey(change.New)))
+	}
+	for _, variable := range p.Deletes {
+		lines = append(lines, fmt.Sprintf("- %s", planKey(variable)))
+	}
+	if len(lines) == 0 {
+		return "  none\n"
+	}
+	if inFlight {
+		lines[0] += " (sent, may have been applied)"
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
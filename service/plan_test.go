@@ -0,0 +1,58 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ninogresenz/civar/gitlab"
+	"github.com/ninogresenz/civar/service"
+)
+
+func TestNewPlan(t *testing.T) {
+	existing := gitlab.CiVariableList{
+		{Key: "UNCHANGED", Value: "1", VariableType: "env_var", EnvironmentScope: "*"},
+		{Key: "CHANGED", Value: "1", VariableType: "env_var", EnvironmentScope: "*"},
+		{Key: "CHANGED", Value: "1", VariableType: "env_var", EnvironmentScope: "staging"},
+		{Key: "REMOVED", Value: "1", VariableType: "env_var", EnvironmentScope: "*"},
+	}
+	desired := gitlab.CiVariableList{
+		{Key: "UNCHANGED", Value: "1", VariableType: "env_var", EnvironmentScope: "*"},
+		{Key: "CHANGED", Value: "2", VariableType: "env_var", EnvironmentScope: "*"},
+		{Key: "CHANGED", Value: "1", VariableType: "env_var", EnvironmentScope: "staging", Protected: true},
+		{Key: "ADDED", Value: "1", VariableType: "env_var", EnvironmentScope: "*"},
+	}
+
+	plan := service.NewPlan(existing, desired, false)
+	assert.Equal(t, gitlab.CiVariableList{desired[3]}, plan.Creates)
+	assert.Equal(t, []service.Change{{Old: existing[1], New: desired[1]}, {Old: existing[2], New: desired[2]}}, plan.Updates)
+	assert.Equal(t, gitlab.CiVariableList{desired[0]}, plan.Unchanged)
+	assert.Empty(t, plan.Deletes)
+
+	plan = service.NewPlan(existing, desired, true)
+	assert.Equal(t, gitlab.CiVariableList{existing[3]}, plan.Deletes)
+}
+
+func TestPlanString(t *testing.T) {
+	plan := service.Plan{
+		Creates: gitlab.CiVariableList{
+			{Key: "NEW", Value: "new", EnvironmentScope: "*"},
+			{Key: "SECRET", Value: "secret", EnvironmentScope: "*", Masked: true},
+		},
+		Updates: []service.Change{{
+			Old: gitlab.CiVariable{Key: "TOKEN", Value: "old", EnvironmentScope: "production", Masked: true},
+			New: gitlab.CiVariable{Key: "TOKEN", Value: "new", EnvironmentScope: "production", Protected: true},
+		}},
+		Skips: gitlab.CiVariableList{{Key: "SKIPPED", EnvironmentScope: "staging"}},
+	}
+	assert.True(t, plan.HasChanges())
+	assert.Equal(t, `+ [*] NEW = "new"
++ [*] SECRET = (masked)
+~ [production] TOKEN
+    value: (masked) -> (masked)
+    protected: false -> true
+    masked: true -> false
+  [staging] SKIPPED (skipped)
+Plan: 2 to create, 1 to update, 0 to delete, 0 unchanged, 1 skipped.`, plan.String())
+	assert.False(t, service.Plan{Skips: plan.Skips}.HasChanges())
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 
@@ -15,78 +14,105 @@ import (
 )
 
 type CiPrinter interface {
-	Print(data gitlab.CiVariableList) string
+	Print(data gitlab.CiVariableList) (string, error)
 }
 
-func PrinterProvider(format string) (printer CiPrinter) {
+func PrinterProvider(format string) (CiPrinter, error) {
 	switch format {
 	case jsonFormat:
 		// print raw json
-		return jsonPrinter{}
+		return jsonPrinter{}, nil
 	case prettyFormat:
 		// print as table
-		return prettyPrinter{}
+		return prettyPrinter{}, nil
+	case wideFormat:
+		// print as table with all attributes
+		return prettyPrinter{wide: true}, nil
 	case dotenvFormat:
 		// print dotenv format
-		return dotenvPrinter{}
+		return dotenvPrinter{}, nil
+	case yamlFormat:
+		// print yaml grouped by scope
+		return yamlPrinter{}, nil
 	default:
-		log.Fatalf("Not a valid format: %s", format)
+		return nil, fmt.Errorf("not a valid format: %s", format)
 	}
-	return printer
 }
 
-// DotenvPrinter prints values as a dotenv file
+// printerFor creates the printer of the format of options. The terraform and kubernetes formats
+// refer to the target and are configured by options.
+func printerFor(target Target, options GetOptions) (CiPrinter, error) {
+	if options.TfVars != nil && options.Format != terraformFormat {
+		return nil, fmt.Errorf("tfvars are only supported by the %s format", terraformFormat)
+	}
+	switch options.Format {
+	case terraformFormat:
+		return terraformPrinter{target: target, tfvars: options.TfVars != nil}, nil
+	case kubernetesFormat:
+		return kubernetesPrinter{target: target, options: options.Kubernetes}, nil
+	}
+	return PrinterProvider(options.Format)
+}
+
+// DotenvPrinter prints values as a dotenv file.
+// Attributes are kept as annotation comments above the keys.
 type dotenvPrinter struct{}
 
-func (p dotenvPrinter) Print(data gitlab.CiVariableList) string {
+func (p dotenvPrinter) Print(data gitlab.CiVariableList) (string, error) {
 	var scopes []string
-	scopeIds := []string{"*", "staging", "production", "prodtest"}
-	splitVars := p.splitVarsByScope(data)
+	scopeIds, splitVars := p.splitVarsByScope(data)
 	for _, scopeId := range scopeIds {
-		str, err := godotenv.Marshal(p.toMap(splitVars[scopeId]))
-		if err != nil {
-			log.Fatal("could not marshal data to env scopeId")
+		variables := splitVars[scopeId]
+		sortByKey(variables)
+		var lines []string
+		for _, variable := range variables {
+			line, err := godotenv.Marshal(map[string]string{variable.Key: variable.Value})
+			if err != nil {
+				return "", fmt.Errorf("could not marshal variable %s to dotenv: %w", variable.Key, err)
+			}
+			lines = append(lines, annotationOf(variable), line)
 		}
 		var b bytes.Buffer
 		b.WriteString(fmt.Sprintf("# Scope: %v\n", scopeId))
-		b.WriteString(str)
+		b.WriteString(strings.Join(lines, "\n"))
 		scopes = append(scopes, b.String())
 	}
 	dotenvString := strings.Join(scopes, "\n\n")
-	return dotenvString
+	return dotenvString, nil
 }
 
-func (p dotenvPrinter) splitVarsByScope(data gitlab.CiVariableList) map[string]gitlab.CiVariableList {
+// splitVarsByScope groups the variables by scope. The scopes are returned in order of appearance.
+func (p dotenvPrinter) splitVarsByScope(data gitlab.CiVariableList) ([]string, map[string]gitlab.CiVariableList) {
+	var scopeIds []string
 	var scopeMap = make(map[string]gitlab.CiVariableList)
 	for _, variable := range data {
 		scope := variable.EnvironmentScope
 		scopeSlice, present := scopeMap[scope]
 		if !present {
+			scopeIds = append(scopeIds, scope)
 			scopeMap[scope] = gitlab.CiVariableList{}
 			scopeSlice = scopeMap[scope]
 		}
-		variable.Key = strings.Replace(variable.Key, "K8S_SECRET_", "", 1)
 		scopeSlice = append(scopeSlice, variable)
 		scopeMap[scope] = scopeSlice
 	}
-	return scopeMap
+	return scopeIds, scopeMap
 }
 
-func (p dotenvPrinter) toMap(data gitlab.CiVariableList) map[string]string {
-	varMap := make(map[string]string)
-	for _, variable := range data {
-		varMap[variable.Key] = variable.Value
-	}
-	return varMap
+// PrettyPrinter prints values as a table.
+// The wide table adds the optional columns raw, hidden and description.
+type prettyPrinter struct {
+	wide bool
 }
 
-// PrettyPrinter prints values as a table
-type prettyPrinter struct{}
-
-func (p prettyPrinter) Print(data gitlab.CiVariableList) string {
+func (p prettyPrinter) Print(data gitlab.CiVariableList) (string, error) {
 	var buf bytes.Buffer
 	table := tablewriter.NewWriter(&buf)
-	table.SetHeader([]string{"Scope", "Key", "Value", "Type", "Masked", "Protected"})
+	header := []string{"Scope", "Key", "Value", "Type", "Masked", "Protected"}
+	if p.wide {
+		header = append(header, "Raw", "Hidden", "Description")
+	}
+	table.SetHeader(header)
 	table.SetAlignment(tablewriter.ALIGN_LEFT)
 	table.SetAutoWrapText(false)
 	table.SetBorder(false)
@@ -95,26 +121,30 @@ func (p prettyPrinter) Print(data gitlab.CiVariableList) string {
 	table.SetTablePadding("\t") // pad with tabs
 	table.SetNoWhiteSpace(true)
 	for _, v := range data {
-		table.Append([]string{
+		row := []string{
 			v.EnvironmentScope,
 			v.Key,
 			v.Value,
 			v.VariableType,
 			strconv.FormatBool(v.Masked),
 			strconv.FormatBool(v.Protected),
-		})
+		}
+		if p.wide {
+			row = append(row, strconv.FormatBool(v.Raw), strconv.FormatBool(v.Hidden), v.Description)
+		}
+		table.Append(row)
 	}
 	table.Render()
-	return buf.String()
+	return buf.String(), nil
 }
 
 // JsonPrinter prints values as json
 type jsonPrinter struct{}
 
-func (p jsonPrinter) Print(data gitlab.CiVariableList) string {
+func (p jsonPrinter) Print(data gitlab.CiVariableList) (string, error) {
 	prettyJson, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
-	return string(prettyJson)
+	return string(prettyJson), nil
 }
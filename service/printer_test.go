@@ -4,19 +4,90 @@ import (
 	"testing"
 
 	"github.com/bradleyjkemp/cupaloy"
+	"github.com/stretchr/testify/assert"
 
+	"github.com/ninogresenz/civar/gitlab"
 	"github.com/ninogresenz/civar/service"
 )
 
 func TestPrinter(t *testing.T) {
-	tests := map[string]service.CiPrinter{
-		"TestDotenvPrinter": service.PrinterProvider("dotenv"),
-		"TestPrettyPrinter": service.PrinterProvider("pretty"),
-		"TestJsonPrinter":   service.PrinterProvider("json"),
+	tests := map[string]string{
+		"TestDotenvPrinter": "dotenv",
+		"TestPrettyPrinter": "pretty",
+		"TestJsonPrinter":   "json",
+		"TestWidePrinter":   "wide",
+		"TestYamlPrinter":   "yaml",
 	}
-	for testName, printer := range tests {
+	for testName, format := range tests {
 		t.Run(testName, func(t *testing.T) {
-			cupaloy.SnapshotT(t, printer.Print(getVars()))
+			printer, err := service.PrinterProvider(format)
+			assert.NoError(t, err)
+			output, err := printer.Print(getVars())
+			assert.NoError(t, err)
+			cupaloy.SnapshotT(t, output)
+		})
+	}
+
+	_, err := service.PrinterProvider("xml")
+	assert.Error(t, err)
+}
+
+func TestDotenvRoundTrip(t *testing.T) {
+	vars := gitlab.CiVariableList{
+		{Key: "KEY1", VariableType: "env_var", Value: "VALUE1", EnvironmentScope: "review/*"},
+		{Key: "KEY2", VariableType: "env_var", Value: "VALUE2", EnvironmentScope: "qa"},
+		{Key: "KEY1", VariableType: "env_var", Value: "VALUE3", EnvironmentScope: "eu-production", Masked: true, Protected: true},
+		{Key: "KEY2", VariableType: "file", Value: "line1\nline2", EnvironmentScope: "eu-production", Protected: true},
+		{Key: "KEY3", VariableType: "env_var", Value: "VALUE4", EnvironmentScope: "qa", Raw: true, Description: "Used by \"deploy\" @masked"},
+		{Key: "KEY4", VariableType: "env_var", Value: "", EnvironmentScope: "qa", Masked: true, Hidden: true},
+	}
+	printer, _ := service.PrinterProvider("dotenv")
+	output, err := printer.Print(vars)
+	assert.NoError(t, err)
+	parsed, err := service.ParseDotEnv([]byte(output))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, vars, parsed)
+}
+
+func TestYamlRoundTrip(t *testing.T) {
+	vars := gitlab.CiVariableList{
+		{Key: "KEY1", VariableType: "env_var", Value: "VALUE1", EnvironmentScope: "review/*"},
+		{Key: "KEY2", VariableType: "env_var", Value: "true", EnvironmentScope: "*"},
+		{Key: "KEY3", VariableType: "env_var", Value: "007", EnvironmentScope: "*"},
+		{Key: "KEY1", VariableType: "env_var", Value: "VALUE3", EnvironmentScope: "eu-production", Masked: true, Protected: true},
+		{Key: "KEY2", VariableType: "file", Value: "-----BEGIN CERTIFICATE-----\nline2\n-----END CERTIFICATE-----\n", EnvironmentScope: "eu-production", Protected: true},
+		{Key: "KEY3", VariableType: "env_var", Value: "VALUE4", EnvironmentScope: "qa", Raw: true, Description: "Used by \"deploy\": #1"},
+		{Key: "KEY4", VariableType: "env_var", Value: "", EnvironmentScope: "qa", Masked: true, Hidden: true},
+	}
+	printer, _ := service.PrinterProvider("yaml")
+	output, err := printer.Print(vars)
+	assert.NoError(t, err)
+	assert.Contains(t, output, "    value: |\n      -----BEGIN CERTIFICATE-----\n")
+	parsed, err := service.ParseYaml([]byte(output))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, vars, parsed)
+
+	_, err = service.ParseYaml([]byte("- KEY: value"))
+	assert.Error(t, err)
+}
+
+func TestExportedAttributesReplaceExisting(t *testing.T) {
+	exported := gitlab.CiVariableList{{Key: "KEY", VariableType: "env_var", Value: "1", EnvironmentScope: "*"}}
+	existing := gitlab.CiVariableList{{Key: "KEY", VariableType: "file", Value: "1", Protected: true, Masked: true, Raw: true, Description: "old", EnvironmentScope: "*"}}
+	parsers := map[string]func([]byte) ([]gitlab.CiVariable, error){
+		"dotenv": service.ParseDotEnv,
+		"yaml":   service.ParseYaml,
+	}
+	for format, parse := range parsers {
+		t.Run(format, func(t *testing.T) {
+			printer, _ := service.PrinterProvider(format)
+			output, err := printer.Print(exported)
+			assert.NoError(t, err)
+			parsed, err := parse([]byte(output))
+			assert.NoError(t, err)
+
+			plan := service.NewPlan(existing, parsed, false)
+			assert.Equal(t, []service.Change{{Old: existing[0], New: exported[0]}}, plan.Updates)
 		})
 	}
 }
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ninogresenz/civar/gitlab"
+)
+
+// ParseScopeMapping reads environment scope mappings given as from=to pairs, e.g. "staging=review/*".
+// The mappings of the file, one pair per line, are read first. Empty lines and lines starting with # are ignored.
+func ParseScopeMapping(mappings []string, file string) (map[string]string, error) {
+	var pairs []string
+	if len(file) > 0 {
+		content, err := getFileContent(file)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if len(line) == 0 || strings.HasPrefix(line, "#") {
+				continue
+			}
+			pairs = append(pairs, line)
+		}
+	}
+	pairs = append(pairs, mappings...)
+
+	mapping := map[string]string{}
+	for _, pair := range pairs {
+		from, to, found := strings.Cut(pair, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !found || len(from) == 0 || len(to) == 0 {
+			return nil, fmt.Errorf("scope mapping must look like from=to: %s", pair)
+		}
+		mapping[from] = to
+	}
+	return mapping, nil
+}
+
+// MapScopes returns a copy of the variables with their environment scopes rewritten by mapping.
+// Scopes missing in mapping are kept. Two variables ending up with the same key and scope are an error.
+func MapScopes(data gitlab.CiVariableList, mapping map[string]string) (gitlab.CiVariableList, error) {
+	mapped := make(gitlab.CiVariableList, 0, len(data))
+	for _, variable := range data {
+		if to, found := mapping[variable.EnvironmentScope]; found {
+			variable.EnvironmentScope = to
+		}
+		if mapped.Includes(variable) {
+			return nil, fmt.Errorf("scope mapping results in duplicate variable [key: %s, scope: %s]", variable.Key, variable.EnvironmentScope)
+		}
+		mapped.Push(variable)
+	}
+	return mapped, nil
+}
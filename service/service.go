@@ -3,16 +3,15 @@ package service
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"log"
 	"os"
 	"strings"
 
 	"github.com/joho/godotenv"
-	"github.com/spf13/cobra"
 
 	"github.com/ninogresenz/civar/gitlab"
 )
@@ -22,167 +21,444 @@ const (
 	ScopePrefix = "# Scope: "
 
 	// scopes
-	AllScope      = "*"
-	StageScope    = "staging"
-	ProdScope     = "production"
-	ProdTestScope = "prodtest"
+	AllScope = "*"
 
 	// formats
-	jsonFormat   = "json"
-	prettyFormat = "pretty"
-	dotenvFormat = "dotenv"
+	jsonFormat       = "json"
+	prettyFormat     = "pretty"
+	wideFormat       = "wide"
+	dotenvFormat     = "dotenv"
+	yamlFormat       = "yaml"
+	terraformFormat  = "terraform"
+	kubernetesFormat = "kubernetes"
 )
 
 type Service interface {
-	Search()
-	Get(format string, scopeFilter string)
-	Create(format string, k8s bool, fileFlag string)
-	Update(format string, k8s bool, fileFlag string)
+	Search(ctx context.Context, w io.Writer, term string) ([]gitlab.Project, error)
+	Get(ctx context.Context, w io.Writer, target Target, options GetOptions) (gitlab.CiVariableList, error)
+	Create(ctx context.Context, w io.Writer, target Target, input Input, dryRun bool) (Plan, error)
+	Update(ctx context.Context, w io.Writer, target Target, input Input, dryRun bool) (Plan, error)
+	UpdateAttributes(ctx context.Context, w io.Writer, target Target, keys []string, scopeFilter string, settings []string, dryRun bool) (Plan, error)
+	Delete(ctx context.Context, target Target, options DeleteOptions) (Plan, error)
+	Apply(ctx context.Context, w io.Writer, target Target, input Input, prune bool, dryRun bool) (Plan, error)
+	Diff(ctx context.Context, w io.Writer, target Target, input Input, output string) (Diff, error)
+	DiffTargets(ctx context.Context, w io.Writer, from Target, to Target, fromScope string, toScope string, output string) (Diff, error)
 }
 
-func NewService(api gitlab.Api, cmd *cobra.Command, args []string) Service {
-	return &service{api, cmd, args}
+// ErrAborted is returned by Delete if the deletion was not confirmed.
+var ErrAborted = errors.New("aborted")
+
+// GetOptions control how Get prints the variables.
+type GetOptions struct {
+	Format string
+	// Scope prints only the variables of this environment scope if set
+	Scope string
+	// Transforms rewrite the printed keys
+	Transforms KeyTransforms
+	// TfVars receives the values of the terraform format, which are then referenced instead of being inlined
+	TfVars io.Writer
+	// Kubernetes configures the manifests of the kubernetes format
+	Kubernetes KubernetesOptions
+}
+
+// Input describes where and how variables are read.
+type Input struct {
+	Format string
+	// K8s adds the K8S_SECRET_ prefix to all keys
+	K8s bool
+	// KeyTransforms rewrite all keys after the K8s prefix was added, see ParseKeyTransforms
+	KeyTransforms []string
+	// File is read instead of Stdin if set
+	File  string
+	Stdin io.Reader
+	// Vars are used as they are instead of reading File or Stdin if not nil
+	Vars gitlab.CiVariableList
+	// ScopeMappings rewrite environment scopes given as from=to pairs, e.g. staging=review/*
+	ScopeMappings []string
+	// ScopeMappingFile holds additional mappings, one pair per line
+	ScopeMappingFile string
+}
+
+// transforms combines the K8s preset with the KeyTransforms.
+func (i Input) transforms() (KeyTransforms, error) {
+	transforms, err := ParseKeyTransforms(i.KeyTransforms)
+	if err != nil {
+		return nil, err
+	}
+	if i.K8s {
+		transforms = append(K8sImport(), transforms...)
+	}
+	return transforms, nil
+}
+
+func (i Input) name() string {
+	if i.Vars != nil {
+		return "input"
+	}
+	if len(i.File) > 0 {
+		return i.File
+	}
+	return "stdin"
+}
+
+// DeleteOptions selects the variables removed by Delete.
+// If neither Keys nor Scope is set, the variables are read from Input.
+type DeleteOptions struct {
+	Keys  []string
+	Scope string
+	// K8s adds the K8S_SECRET_ prefix to Keys
+	K8s   bool
+	Input Input
+	// Confirm is asked before deleting, nil deletes without confirmation
+	Confirm func(target Target, data gitlab.CiVariableList) (bool, error)
+}
+
+func NewService(api gitlab.Api) Service {
+	return &service{api}
 }
 
 type service struct {
-	api  gitlab.Api
-	cmd  *cobra.Command
-	args []string
+	api gitlab.Api
 }
 
-func (s *service) Search() {
-	data, err := s.api.Search(s.args[0])
+func (s *service) Search(ctx context.Context, w io.Writer, term string) ([]gitlab.Project, error) {
+	data, err := s.api.Search(ctx, term)
 	if err != nil {
-		log.Fatalf("could not get vars: %v", err)
+		return nil, fmt.Errorf("could not search projects: %w", err)
 	}
 	for _, p := range data {
-		fmt.Printf("%s  %s\n", p.PathWithNamespace, p.WebUrl)
+		_, _ = fmt.Fprintf(w, "%s  %s\n", p.PathWithNamespace, p.WebUrl)
 	}
+	return data, nil
 }
 
-func (s *service) Get(format string, scopeFilter string) {
-	data, err := s.api.GetProjectVars(s.args[0])
+// Get prints the variables of the target with their keys rewritten by the transforms of the options.
+// The returned variables keep their original keys.
+func (s *service) Get(ctx context.Context, w io.Writer, target Target, options GetOptions) (gitlab.CiVariableList, error) {
+	printer, err := printerFor(target, options)
 	if err != nil {
-		log.Fatalf("could not get vars: %v", err)
+		return nil, err
+	}
+	data, err := s.getVars(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("could not get vars: %w", err)
 	}
 	// apply scope filter if applicable
+	if len(options.Scope) > 0 {
+		data = ApplyScopeFilter(data, options.Scope)
+	}
+	printed := options.Transforms.Apply(data)
+	if terraform, ok := printer.(terraformPrinter); ok && options.TfVars != nil {
+		if _, err := fmt.Fprintln(options.TfVars, terraform.TfVars(printed)); err != nil {
+			return data, err
+		}
+	}
+	return data, printVars(w, printer, printed)
+}
+
+// Create creates all variables of the input which do not exist yet.
+// Skipped variables read from a file or stdin are printed in the input format.
+// Hidden variables without a value are skipped as well.
+// With dryRun, the plan is printed instead of being executed.
+func (s *service) Create(ctx context.Context, w io.Writer, target Target, input Input, dryRun bool) (Plan, error) {
+	data, existingVars, err := s.readVars(ctx, target, input)
+	if err != nil {
+		return Plan{}, err
+	}
+	plan := NewPlan(existingVars, data, false).createOnly().skipHidden()
+	if dryRun {
+		_, err = fmt.Fprintln(w, plan.String())
+		return plan, err
+	}
+	if err := s.execute(ctx, target, plan); err != nil {
+		return plan, err
+	}
+	if len(plan.Skips) > 0 && input.Vars == nil {
+		printer, err := PrinterProvider(input.Format)
+		if err != nil {
+			return plan, err
+		}
+		return plan, printVars(w, printer, plan.Skips)
+	}
+	return plan, nil
+}
+
+// Update updates all changed variables of the input which already exist.
+// Skipped variables read from a file or stdin are printed as json.
+// Hidden variables without a value are skipped as well.
+// With dryRun, the plan is printed instead of being executed.
+func (s *service) Update(ctx context.Context, w io.Writer, target Target, input Input, dryRun bool) (Plan, error) {
+	data, existingVars, err := s.readVars(ctx, target, input)
+	if err != nil {
+		return Plan{}, err
+	}
+	plan := NewPlan(existingVars, data, false).updateOnly().skipHidden()
+	if dryRun {
+		_, err = fmt.Fprintln(w, plan.String())
+		return plan, err
+	}
+	if err := s.execute(ctx, target, plan); err != nil {
+		return plan, err
+	}
+	if len(plan.Skips) > 0 && input.Vars == nil {
+		return plan, printVars(w, jsonPrinter{}, plan.Skips)
+	}
+	return plan, nil
+}
+
+// UpdateAttributes sets attributes like protected=true on existing variables without changing their values.
+// The variables are selected by keys and scopeFilter, all variables are selected if both are empty.
+// Hidden variables are skipped.
+func (s *service) UpdateAttributes(ctx context.Context, w io.Writer, target Target, keys []string, scopeFilter string, settings []string, dryRun bool) (Plan, error) {
+	existingVars, err := s.getVars(ctx, target)
+	if err != nil {
+		return Plan{}, fmt.Errorf("could not get vars: %w", err)
+	}
+	selectedVars := ApplyKeyFilter(existingVars, keys)
 	if len(scopeFilter) > 0 {
-		data = ApplyScopeFilter(data, scopeFilter)
+		selectedVars = ApplyScopeFilter(selectedVars, scopeFilter)
+	}
+	data, err := ApplySettings(selectedVars, settings)
+	if err != nil {
+		return Plan{}, err
+	}
+	plan := NewPlan(existingVars, data, false).updateOnly().skipHidden()
+	if dryRun {
+		_, err = fmt.Fprintln(w, plan.String())
+		return plan, err
 	}
-	printer := PrinterProvider(format)
-	fmt.Println(printer.Print(data))
+	return plan, s.execute(ctx, target, plan)
 }
 
-func (s *service) Create(format string, k8s bool, fileFlag string) {
-	if format != dotenvFormat && format != jsonFormat {
-		log.Fatal("format must be one of [json | dotenv]")
+// Apply creates missing and updates changed variables so the target matches the input.
+// With prune, variables missing in the input are deleted as well. Hidden variables without a value are skipped.
+// With dryRun, the plan is printed instead of being executed.
+func (s *service) Apply(ctx context.Context, w io.Writer, target Target, input Input, prune bool, dryRun bool) (Plan, error) {
+	data, existingVars, err := s.readVars(ctx, target, input)
+	if err != nil {
+		return Plan{}, err
 	}
-	input := getInput(fileFlag, s.cmd.InOrStdin())
-	data := parseInput(format, input)
-	if k8s {
-		data = AddPrefix(data)
+	plan := NewPlan(existingVars, data, prune).skipHidden()
+	if dryRun {
+		_, err = fmt.Fprintln(w, plan.String())
+		return plan, err
 	}
-	project := s.args[0]
+	return plan, s.execute(ctx, target, plan)
+}
 
-	existingVars, err := s.api.GetProjectVars(project)
+// Diff compares the variables of the target with the input and prints the differences.
+func (s *service) Diff(ctx context.Context, w io.Writer, target Target, input Input, output string) (Diff, error) {
+	data, existingVars, err := s.readVars(ctx, target, input)
 	if err != nil {
-		log.Fatalf("could not get vars: %v", err)
+		return nil, err
 	}
-	notCreatedVars := make(gitlab.CiVariableList, 0)
-	for _, variable := range data {
-		if existingVars.Includes(variable) {
-			notCreatedVars.Push(variable)
-			continue
-		}
-		_, err := s.api.CreateVar(project, variable)
+	diff := NewDiff(existingVars, data)
+	return diff, printDiff(w, diff, output, target.String(), input.name())
+}
+
+// DiffTargets compares the variables of two targets and prints the differences.
+// If scopes are given, only the variables of fromScope and toScope are compared by key.
+func (s *service) DiffTargets(ctx context.Context, w io.Writer, from Target, to Target, fromScope string, toScope string, output string) (Diff, error) {
+	fromVars, err := s.getVars(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("could not get vars: %w", err)
+	}
+	toVars := fromVars
+	if to != from {
+		toVars, err = s.getVars(ctx, to)
 		if err != nil {
-			log.Fatalf("could not create variable [%s]: %v", variable.Key, err)
+			return nil, fmt.Errorf("could not get vars: %w", err)
 		}
 	}
-	if len(notCreatedVars) > 0 {
-		printer := PrinterProvider(format)
-		fmt.Println(printer.Print(notCreatedVars))
-		_, _ = os.Stderr.WriteString(fmt.Sprintf("Duplicate variables skipped: %d/%d\n", len(notCreatedVars), len(data)))
+	fromName, toName := from.String(), to.String()
+	if len(fromScope) > 0 || len(toScope) > 0 {
+		if len(toScope) == 0 {
+			toScope = fromScope
+		}
+		if len(fromScope) == 0 {
+			fromScope = toScope
+		}
+		// variables of both scopes share one label to be matched by key only
+		label := fromScope
+		if fromScope != toScope {
+			label = fmt.Sprintf("%s -> %s", fromScope, toScope)
+		}
+		fromVars = relabelScope(ApplyScopeFilter(fromVars, fromScope), label)
+		toVars = relabelScope(ApplyScopeFilter(toVars, toScope), label)
+		fromName = fmt.Sprintf("%s [%s]", fromName, fromScope)
+		toName = fmt.Sprintf("%s [%s]", toName, toScope)
 	}
+	diff := NewDiff(fromVars, toVars)
+	return diff, printDiff(w, diff, output, fromName, toName)
 }
 
-func (s *service) Update(format string, k8s bool, fileFlag string) {
-	input := getInput(fileFlag, s.cmd.InOrStdin())
-	data := parseInput(format, input)
-	if k8s {
-		data = AddPrefix(data)
-	}
-	project := s.args[0]
-
-	existingVars, err := s.api.GetProjectVars(project)
+// Delete removes the selected variables. The returned plan lists the deleted variables
+// and the input variables skipped because they do not exist.
+func (s *service) Delete(ctx context.Context, target Target, options DeleteOptions) (Plan, error) {
+	existingVars, err := s.getVars(ctx, target)
 	if err != nil {
-		log.Fatalf("could not get vars: %v", err)
+		return Plan{}, fmt.Errorf("could not get vars: %w", err)
 	}
 
-	notUpdatedVars := make(gitlab.CiVariableList, 0)
-	for _, variable := range data {
-		if !existingVars.Includes(variable) {
-			notUpdatedVars = append(notUpdatedVars, variable)
-			continue
+	plan := Plan{}
+	if len(options.Keys) > 0 || len(options.Scope) > 0 {
+		keys := options.Keys
+		if options.K8s {
+			keys = K8sImport().Keys(keys)
 		}
-		_, err := s.api.UpdateVar(project, variable)
+		plan.Deletes = ApplyKeyFilter(existingVars, keys)
+		if len(options.Scope) > 0 {
+			plan.Deletes = ApplyScopeFilter(plan.Deletes, options.Scope)
+		}
+	} else {
+		data, err := readInput(options.Input)
 		if err != nil {
-			log.Fatalf("could not update variable [key: %s, scope:%s]: %v", variable.Key, variable.EnvironmentScope, err)
+			return Plan{}, err
+		}
+		for _, variable := range data {
+			if !existingVars.Includes(variable) {
+				plan.Skips.Push(variable)
+				continue
+			}
+			plan.Deletes.Push(variable)
 		}
 	}
-	if len(notUpdatedVars) > 0 {
-		prettyJson, err := json.MarshalIndent(notUpdatedVars, "", "  ")
+
+	if len(plan.Deletes) == 0 {
+		return plan, nil
+	}
+	if options.Confirm != nil {
+		confirmed, err := options.Confirm(target, plan.Deletes)
 		if err != nil {
-			log.Fatal(err)
+			return plan, err
+		}
+		if !confirmed {
+			return plan, ErrAborted
 		}
-		fmt.Println(string(prettyJson))
-		_, _ = os.Stderr.WriteString(fmt.Sprintf("variables skipped because not existent: %d/%d\n", len(notUpdatedVars), len(data)))
 	}
+	return plan, s.execute(ctx, target, plan)
 }
 
-func getInput(file string, stdin io.Reader) []byte {
+// readVars parses the input and fetches the existing variables of the target.
+func (s *service) readVars(ctx context.Context, target Target, input Input) (data gitlab.CiVariableList, existingVars gitlab.CiVariableList, err error) {
+	data, err = readInput(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkTarget(target, data); err != nil {
+		return nil, nil, err
+	}
+	existingVars, err = s.getVars(ctx, target)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get vars: %w", err)
+	}
+	return data, existingVars, nil
+}
+
+func readInput(input Input) (gitlab.CiVariableList, error) {
+	data, err := parseVars(input)
+	if err != nil {
+		return nil, err
+	}
+	transforms, err := input.transforms()
+	if err != nil {
+		return nil, err
+	}
+	data = transforms.Apply(data)
+	if len(input.ScopeMappings) > 0 || len(input.ScopeMappingFile) > 0 {
+		mapping, err := ParseScopeMapping(input.ScopeMappings, input.ScopeMappingFile)
+		if err != nil {
+			return nil, err
+		}
+		return MapScopes(data, mapping)
+	}
+	return data, nil
+}
+
+func parseVars(input Input) (gitlab.CiVariableList, error) {
+	if input.Vars != nil {
+		return append(gitlab.CiVariableList{}, input.Vars...), nil
+	}
+	if input.Format != dotenvFormat && input.Format != jsonFormat && input.Format != yamlFormat {
+		return nil, errors.New("format must be one of [json | dotenv | yaml]")
+	}
+	content, err := getInput(input.File, input.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	return parseInput(input.Format, content)
+}
+
+func printVars(w io.Writer, printer CiPrinter, data gitlab.CiVariableList) error {
+	output, err := printer.Print(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, output)
+	return err
+}
+
+func printDiff(w io.Writer, diff Diff, format string, fromName string, toName string) error {
+	output, err := diff.Print(format, fromName, toName)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, output)
+	return err
+}
+
+func getInput(file string, stdin io.Reader) ([]byte, error) {
 	if len(file) > 0 {
 		return getFileContent(file)
 	}
-	input, err := ioutil.ReadAll(stdin)
+	input, err := io.ReadAll(stdin)
 	if err != nil {
-		log.Fatalf("could not get input from stdin: %v", err)
+		return nil, fmt.Errorf("could not get input from stdin: %w", err)
 	}
-	return input
+	return input, nil
 }
 
-func parseInput(format string, input []byte) []gitlab.CiVariable {
-	if format == dotenvFormat {
+func parseInput(format string, input []byte) ([]gitlab.CiVariable, error) {
+	switch format {
+	case dotenvFormat:
 		return ParseDotEnv(input)
+	case yamlFormat:
+		return ParseYaml(input)
 	}
-	var data []gitlab.CiVariable
-	err := json.Unmarshal(input, &data)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return data
+	return ParseJson(input)
 }
 
-func AddPrefix(data []gitlab.CiVariable) []gitlab.CiVariable {
+// ParseJson reads a list of variables. Attributes missing in the input are marked as omitted.
+func ParseJson(input []byte) ([]gitlab.CiVariable, error) {
+	var data []gitlab.CiVariable
+	if err := json.Unmarshal(input, &data); err != nil {
+		return nil, fmt.Errorf("could not parse json: %w", err)
+	}
+	var fields []map[string]json.RawMessage
+	if err := json.Unmarshal(input, &fields); err != nil {
+		return nil, fmt.Errorf("could not parse json: %w", err)
+	}
 	for i := range data {
-		if strings.Contains(data[i].Key, K8sPrefix) {
-			continue
+		var names []string
+		for name := range fields[i] {
+			names = append(names, name)
 		}
-		data[i].Key = K8sPrefix + data[i].Key
+		data[i].Omitted = omittedAttributes(names)
 	}
-	return data
+	return data, nil
 }
 
-func getFileContent(filepath string) []byte {
-	_, err := os.Stat(filepath)
-	if err != nil {
-		log.Fatalf("Could not receive file info from: %v\n", filepath)
-	}
+// AddPrefix adds the K8S_SECRET_ prefix to all keys which do not start with it yet.
+func AddPrefix(data []gitlab.CiVariable) []gitlab.CiVariable {
+	return K8sImport().Apply(data)
+}
+
+func getFileContent(filepath string) ([]byte, error) {
 	fileContent, err := os.ReadFile(filepath)
 	if err != nil {
-		log.Fatalf("Could not read file: %v\n", filepath)
+		return nil, fmt.Errorf("could not read file: %w", err)
 	}
-	return fileContent
+	return fileContent, nil
 }
 
 func ApplyScopeFilter(data gitlab.CiVariableList, scopeFilter string) gitlab.CiVariableList {
@@ -196,35 +472,78 @@ func ApplyScopeFilter(data gitlab.CiVariableList, scopeFilter string) gitlab.CiV
 	return filteredList
 }
 
-func ParseDotEnv(input []byte) []gitlab.CiVariable {
+// relabelScope returns a copy of the variables with the given environment scope.
+func relabelScope(data gitlab.CiVariableList, scope string) gitlab.CiVariableList {
+	relabeled := make(gitlab.CiVariableList, len(data))
+	for i, variable := range data {
+		variable.EnvironmentScope = scope
+		relabeled[i] = variable
+	}
+	return relabeled
+}
+
+// ApplyKeyFilter returns the variables matching one of the given keys.
+// An empty key list matches all variables.
+func ApplyKeyFilter(data gitlab.CiVariableList, keys []string) gitlab.CiVariableList {
+	if len(keys) == 0 {
+		return data
+	}
+	var filteredList gitlab.CiVariableList
+	for _, envVar := range data {
+		for _, key := range keys {
+			if envVar.Key == key {
+				filteredList = append(filteredList, envVar)
+				break
+			}
+		}
+	}
+	return filteredList
+}
+
+// ParseDotEnv reads variables grouped by "# Scope: " comments. Variables before the first
+// scope comment belong to the * scope. Annotation comments like "# @masked @protected"
+// set the attributes of the following key.
+func ParseDotEnv(input []byte) ([]gitlab.CiVariable, error) {
 	scanner := bufio.NewScanner(bytes.NewReader(input))
-	var allBuffer bytes.Buffer
-	var stagingBuffer bytes.Buffer
-	var productionBuffer bytes.Buffer
-	var prodtestBuffer bytes.Buffer
-	scope := "*"
+	buffers := make(map[string]*bytes.Buffer)
+	annotations := make(map[string]map[string]string)
+	var scopes []string
+	scope := AllScope
+	annotation := ""
 	for scanner.Scan() {
-		if strings.Contains(scanner.Text(), ScopePrefix) {
-			scope = strings.Replace(scanner.Text(), ScopePrefix, "", 1)
-			scanner.Scan()
+		line := scanner.Text()
+		if strings.HasPrefix(line, ScopePrefix) {
+			scope = strings.TrimSpace(strings.Replace(line, ScopePrefix, "", 1))
+			annotation = ""
+			continue
+		}
+		if strings.HasPrefix(line, AnnotationPrefix) {
+			annotation = line
+			continue
+		}
+		buf, present := buffers[scope]
+		if !present {
+			buf = &bytes.Buffer{}
+			buffers[scope] = buf
+			annotations[scope] = make(map[string]string)
+			scopes = append(scopes, scope)
 		}
-		switch scope {
-		case AllScope:
-			allBuffer.Write(append(scanner.Bytes(), '\n'))
-		case ProdScope:
-			productionBuffer.Write(append(scanner.Bytes(), '\n'))
-		case StageScope:
-			stagingBuffer.Write(append(scanner.Bytes(), '\n'))
-		case ProdTestScope:
-			prodtestBuffer.Write(append(scanner.Bytes(), '\n'))
+		// an annotation only belongs to the key directly below it
+		if len(annotation) > 0 && len(strings.TrimSpace(line)) > 0 && !strings.HasPrefix(line, "#") {
+			annotations[scope][dotenvKey(line)] = annotation
 		}
+		annotation = ""
+		buf.Write(append(scanner.Bytes(), '\n'))
 	}
-	return join(
-		toStruct(toMap(allBuffer), AllScope),
-		toStruct(toMap(stagingBuffer), StageScope),
-		toStruct(toMap(productionBuffer), ProdScope),
-		toStruct(toMap(prodtestBuffer), ProdTestScope),
-	)
+	var variables [][]gitlab.CiVariable
+	for _, scope := range scopes {
+		env, err := godotenv.Unmarshal(buffers[scope].String())
+		if err != nil {
+			return nil, fmt.Errorf("could not parse dotenv of scope %s: %w", scope, err)
+		}
+		variables = append(variables, toStruct(env, scope, annotations[scope]))
+	}
+	return join(variables...), nil
 }
 
 func join[Type interface{}](vars ...[]Type) (allVars []Type) {
@@ -234,25 +553,19 @@ func join[Type interface{}](vars ...[]Type) (allVars []Type) {
 	return allVars
 }
 
-func toMap(buf bytes.Buffer) map[string]string {
-	env, err := godotenv.Unmarshal(buf.String())
-	if err != nil {
-		log.Fatal("Could not unmarshal dotenv structure")
-	}
-	return env
-}
-
-func toStruct(envMap map[string]string, scope string) []gitlab.CiVariable {
+func toStruct(envMap map[string]string, scope string, annotations map[string]string) []gitlab.CiVariable {
 	var variables []gitlab.CiVariable
 	for key, value := range envMap {
 		variable := gitlab.CiVariable{
 			Key:              key,
 			Value:            value,
 			EnvironmentScope: scope,
-			VariableType:     "env_var",
+			VariableType:     envVariableType,
 			Protected:        false,
 			Masked:           false,
+			Omitted:          gitlab.AllAttributes,
 		}
+		applyAnnotation(annotations[key], &variable)
 		variables = append(variables, variable)
 	}
 	return variables
@@ -1,6 +1,12 @@
 package service_test
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/bradleyjkemp/cupaloy"
@@ -11,7 +17,106 @@ import (
 )
 
 func TestGet(t *testing.T) {
+	api := &fakeApi{projects: map[string]gitlab.CiVariableList{"a": getVars()}}
+	var out bytes.Buffer
 
+	data, err := service.NewService(api).Get(context.Background(), &out, project("a"), service.GetOptions{Format: "dotenv", Scope: "staging"})
+	assert.NoError(t, err)
+	assert.Equal(t, service.ApplyScopeFilter(getVars(), "staging"), data)
+	plain := "# @unmasked @unprotected @env @expanded @description=\"\"\n"
+	assert.Equal(t, "# Scope: staging\n"+plain+"TEST_KEY1=\"MY_VARIABLE1\"\n"+plain+"TEST_KEY2=\"MY_VARIABLE2\"\n"+plain+"TEST_KEY3=\"MY_VARIABLE3\"\n", out.String())
+
+	out.Reset()
+	transforms := service.KeyTransforms{service.StripKeyPrefix("TEST_"), strings.ToLower}
+	_, err = service.NewService(api).Get(context.Background(), &out, project("a"), service.GetOptions{Format: "dotenv", Scope: "staging", Transforms: transforms})
+	assert.NoError(t, err)
+	assert.Equal(t, "# Scope: staging\n"+plain+"key1=\"MY_VARIABLE1\"\n"+plain+"key2=\"MY_VARIABLE2\"\n"+plain+"key3=\"MY_VARIABLE3\"\n", out.String())
+
+	out.Reset()
+	var tfvars bytes.Buffer
+	_, err = service.NewService(api).Get(context.Background(), &out, project("a"), service.GetOptions{Format: "terraform", Scope: "*", TfVars: &tfvars})
+	assert.NoError(t, err)
+	cupaloy.SnapshotT(t, out.String(), tfvars.String())
+
+	_, err = service.NewService(api).Get(context.Background(), &out, project("a"), service.GetOptions{Format: "json", TfVars: &tfvars})
+	assert.Error(t, err)
+
+	_, err = service.NewService(api).Get(context.Background(), &out, project("a"), service.GetOptions{Format: "xml"})
+	assert.Error(t, err)
+
+	_, err = service.NewService(api).Get(context.Background(), &out, project("missing"), service.GetOptions{Format: "json"})
+	assert.ErrorIs(t, err, gitlab.ErrNotFound)
+}
+
+func TestGetTerraform(t *testing.T) {
+	api := &fakeApi{projects: map[string]gitlab.CiVariableList{"a/b": {
+		{Key: "TEMPLATE", VariableType: "env_var", Value: "${HOME} \"%{x}\"\\\n", EnvironmentScope: "review/*"},
+		{Key: "TEMPLATE", VariableType: "file", Value: "1", Masked: true, Description: "copy", EnvironmentScope: "review_*"},
+	}}}
+	var out bytes.Buffer
+
+	_, err := service.NewService(api).Get(context.Background(), &out, project("a/b"), service.GetOptions{Format: "terraform"})
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), `resource "gitlab_project_variable" "review___template" {`)
+	assert.Contains(t, out.String(), `resource "gitlab_project_variable" "review___template_2" {`)
+	assert.Contains(t, out.String(), `value             = "$${HOME} \"%%{x}\"\\\n"`)
+	assert.Contains(t, out.String(), `id = "a/b:TEMPLATE:review/*"`)
+	assert.Contains(t, out.String(), `description       = "copy"`)
+
+	api.projects["a/b"] = gitlab.CiVariableList{
+		{Key: "SECRET", VariableType: "env_var", Masked: true, Hidden: true, EnvironmentScope: "*"},
+		{Key: "TOKEN", VariableType: "env_var", Value: "token", EnvironmentScope: "*"},
+	}
+	var tfvars bytes.Buffer
+	out.Reset()
+	_, err = service.NewService(api).Get(context.Background(), &out, project("a/b"), service.GetOptions{Format: "terraform", TfVars: &tfvars})
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "  lifecycle {\n    ignore_changes = [value]\n  }\n}")
+	assert.Equal(t, 1, strings.Count(out.String(), "ignore_changes"))
+	assert.Equal(t, "ci_variable_values = {\n  \"all_token\" = \"token\"\n}\n", tfvars.String())
+}
+
+func TestGetKubernetes(t *testing.T) {
+	api := &fakeApi{projects: map[string]gitlab.CiVariableList{"team/Web_App": {
+		{Key: "PASSWORD", VariableType: "env_var", Value: "secret", Masked: true, EnvironmentScope: "production"},
+		{Key: "HOST", VariableType: "env_var", Value: "example.com", EnvironmentScope: "production"},
+		{Key: "HOST", VariableType: "env_var", Value: "localhost", EnvironmentScope: "*"},
+		{Key: "HIDDEN", VariableType: "env_var", Masked: true, Hidden: true, EnvironmentScope: "production"},
+	}}}
+	var out bytes.Buffer
+	options := service.GetOptions{
+		Format:     "kubernetes",
+		Scope:      "production",
+		Kubernetes: service.KubernetesOptions{Namespace: "apps", Labels: map[string]string{"app": "web"}, ConfigMap: true},
+	}
+
+	_, err := service.NewService(api).Get(context.Background(), &out, project("team/Web_App"), options)
+	assert.NoError(t, err)
+	assert.Equal(t, `apiVersion: v1
+kind: Secret
+metadata:
+  name: web-app
+  namespace: apps
+  labels:
+    app: web
+type: Opaque
+data:
+  PASSWORD: c2VjcmV0
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-app
+  namespace: apps
+  labels:
+    app: web
+data:
+  HOST: example.com
+`, out.String())
+
+	out.Reset()
+	_, err = service.NewService(api).Get(context.Background(), &out, project("team/Web_App"), service.GetOptions{Format: "kubernetes"})
+	assert.ErrorContains(t, err, "single scope")
 }
 
 func TestApplyScopeFilter(t *testing.T) {
@@ -19,6 +124,15 @@ func TestApplyScopeFilter(t *testing.T) {
 	cupaloy.SnapshotT(t, output)
 }
 
+func TestApplyKeyFilter(t *testing.T) {
+	output := service.ApplyKeyFilter(getVars(), []string{"TEST_KEY1", "TEST_KEY3"})
+	assert.Len(t, output, 6)
+	for _, variable := range output {
+		assert.Contains(t, []string{"TEST_KEY1", "TEST_KEY3"}, variable.Key)
+	}
+	assert.Equal(t, getVars(), service.ApplyKeyFilter(getVars(), nil))
+}
+
 func TestParseDotEnv(t *testing.T) {
 	input := []byte(`# Scope: *
 TEST_KEY1="MY_VARIABLE1"
@@ -36,7 +150,8 @@ TEST_KEY2="MY_VARIABLE2"
 TEST_KEY3="MY_VARIABLE3"
 `)
 
-	actual := service.ParseDotEnv(input)
+	actual, err := service.ParseDotEnv(input)
+	assert.NoError(t, err)
 
 	assert.ElementsMatch(t, actual, []gitlab.CiVariable{
 		{
@@ -45,6 +160,7 @@ TEST_KEY3="MY_VARIABLE3"
 			Value:            "MY_VARIABLE1",
 			Protected:        false,
 			Masked:           false,
+			Omitted:          gitlab.AllAttributes,
 			EnvironmentScope: "*",
 		}, {
 			Key:              "TEST_KEY2",
@@ -52,6 +168,7 @@ TEST_KEY3="MY_VARIABLE3"
 			Value:            "MY_VARIABLE2",
 			Protected:        false,
 			Masked:           false,
+			Omitted:          gitlab.AllAttributes,
 			EnvironmentScope: "*",
 		}, {
 			Key:              "TEST_KEY3",
@@ -59,6 +176,7 @@ TEST_KEY3="MY_VARIABLE3"
 			Value:            "MY_VARIABLE3",
 			Protected:        false,
 			Masked:           false,
+			Omitted:          gitlab.AllAttributes,
 			EnvironmentScope: "*",
 		}, {
 			Key:              "TEST_KEY1",
@@ -66,6 +184,7 @@ TEST_KEY3="MY_VARIABLE3"
 			Value:            "MY_VARIABLE1",
 			Protected:        false,
 			Masked:           false,
+			Omitted:          gitlab.AllAttributes,
 			EnvironmentScope: "staging",
 		}, {
 			Key:              "TEST_KEY2",
@@ -73,6 +192,7 @@ TEST_KEY3="MY_VARIABLE3"
 			Value:            "MY_VARIABLE2",
 			Protected:        false,
 			Masked:           false,
+			Omitted:          gitlab.AllAttributes,
 			EnvironmentScope: "staging",
 		}, {
 			Key:              "TEST_KEY3",
@@ -80,6 +200,7 @@ TEST_KEY3="MY_VARIABLE3"
 			Value:            "MY_VARIABLE3",
 			Protected:        false,
 			Masked:           false,
+			Omitted:          gitlab.AllAttributes,
 			EnvironmentScope: "staging",
 		}, {
 			Key:              "TEST_KEY1",
@@ -87,6 +208,7 @@ TEST_KEY3="MY_VARIABLE3"
 			Value:            "MY_VARIABLE1",
 			Protected:        false,
 			Masked:           false,
+			Omitted:          gitlab.AllAttributes,
 			EnvironmentScope: "production",
 		}, {
 			Key:              "TEST_KEY2",
@@ -94,6 +216,7 @@ TEST_KEY3="MY_VARIABLE3"
 			Value:            "MY_VARIABLE2",
 			Protected:        false,
 			Masked:           false,
+			Omitted:          gitlab.AllAttributes,
 			EnvironmentScope: "production",
 		}, {
 			Key:              "TEST_KEY3",
@@ -101,11 +224,107 @@ TEST_KEY3="MY_VARIABLE3"
 			Value:            "MY_VARIABLE3",
 			Protected:        false,
 			Masked:           false,
+			Omitted:          gitlab.AllAttributes,
 			EnvironmentScope: "production",
 		},
 	})
 }
 
+func TestParseDotEnvAnnotations(t *testing.T) {
+	input := []byte(`# Scope: production
+# @masked @protected
+TOKEN="secret"
+# @file
+export CERT="line1\nline2"
+PLAIN=value
+`)
+
+	actual, err := service.ParseDotEnv(input)
+	assert.NoError(t, err)
+
+	assert.ElementsMatch(t, actual, []gitlab.CiVariable{
+		{Key: "TOKEN", VariableType: "env_var", Value: "secret", Protected: true, Masked: true, EnvironmentScope: "production",
+			Omitted: gitlab.VariableTypeAttribute | gitlab.RawAttribute | gitlab.DescriptionAttribute},
+		{Key: "CERT", VariableType: "file", Value: "line1\nline2", EnvironmentScope: "production",
+			Omitted: gitlab.AllAttributes &^ gitlab.VariableTypeAttribute},
+		{Key: "PLAIN", VariableType: "env_var", Value: "value", EnvironmentScope: "production", Omitted: gitlab.AllAttributes},
+	})
+
+	actual, err = service.ParseDotEnv([]byte("# @protected\n\n# Scope: staging\nFOO=bar\n# @masked\n\nBAR=foo\n"))
+	assert.NoError(t, err)
+	for _, variable := range actual {
+		assert.False(t, variable.Protected)
+		assert.False(t, variable.Masked)
+	}
+}
+
+func TestApplySettings(t *testing.T) {
+	vars := service.ApplyScopeFilter(getVars(), "production")
+	updated, err := service.ApplySettings(vars, []string{"protected=true", "variable_type=file"})
+	assert.NoError(t, err)
+	for i := range updated {
+		assert.True(t, updated[i].Protected)
+		assert.Equal(t, "file", updated[i].VariableType)
+		assert.Equal(t, vars[i].Value, updated[i].Value)
+		assert.False(t, vars[i].Protected)
+	}
+
+	_, err = service.ApplySettings(vars, []string{"protected=maybe"})
+	assert.Error(t, err)
+	_, err = service.ApplySettings(vars, []string{"unknown=true"})
+	assert.Error(t, err)
+}
+
+func TestMapScopes(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "scopes")
+	assert.NoError(t, os.WriteFile(file, []byte("# template scopes\nstaging = review/*\n\nproduction=eu-production\n"), 0600))
+	mapping, err := service.ParseScopeMapping([]string{"production=us-production"}, file)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"staging": "review/*", "production": "us-production"}, mapping)
+
+	mapped, err := service.MapScopes(getVars(), mapping)
+	assert.NoError(t, err)
+	assert.Len(t, service.ApplyScopeFilter(mapped, "review/*"), len(service.ApplyScopeFilter(getVars(), "staging")))
+	assert.Len(t, service.ApplyScopeFilter(mapped, "us-production"), len(service.ApplyScopeFilter(getVars(), "production")))
+	assert.Len(t, service.ApplyScopeFilter(mapped, "*"), len(service.ApplyScopeFilter(getVars(), "*")))
+	assert.Empty(t, service.ApplyScopeFilter(getVars(), "review/*"))
+
+	_, err = service.MapScopes(getVars(), map[string]string{"staging": "production"})
+	assert.Error(t, err)
+	_, err = service.ParseScopeMapping([]string{"staging"}, "")
+	assert.Error(t, err)
+}
+
+func TestParseKeyTransforms(t *testing.T) {
+	tests := map[string]struct {
+		specs []string
+		key   string
+		want  string
+	}{
+		"add prefix":             {[]string{"add-prefix=APP_"}, "KEY", "APP_KEY"},
+		"add existing prefix":    {[]string{"add-prefix=APP_"}, "APP_KEY", "APP_KEY"},
+		"prefix within key":      {[]string{"add-prefix=K8S_SECRET_"}, "MY_K8S_SECRET_KEY", "K8S_SECRET_MY_K8S_SECRET_KEY"},
+		"strip prefix":           {[]string{"strip-prefix=APP_"}, "APP_KEY", "KEY"},
+		"add suffix":             {[]string{"add-suffix=_V2"}, "KEY", "KEY_V2"},
+		"strip suffix":           {[]string{"strip-suffix=_V2"}, "KEY_V2", "KEY"},
+		"rename":                 {[]string{"rename=^OLD_(.*)=NEW_$1"}, "OLD_KEY", "NEW_KEY"},
+		"rename with = in regex": {[]string{"rename=A=B=C"}, "A=B", "C"},
+		"case":                   {[]string{"lower", "add-prefix=X_", "upper"}, "Key", "X_KEY"},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			transforms, err := service.ParseKeyTransforms(test.specs)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, transforms.Key(test.key))
+		})
+	}
+
+	for _, spec := range []string{"unknown=X", "add-prefix", "rename=(=X", "rename=X"} {
+		_, err := service.ParseKeyTransforms([]string{spec})
+		assert.Error(t, err, spec)
+	}
+}
+
 func TestAddPrefix(t *testing.T) {
 	vars := []gitlab.CiVariable{
 		{
@@ -197,3 +416,207 @@ func getVars() gitlab.CiVariableList {
 		},
 	}
 }
+
+func TestDiffTargets(t *testing.T) {
+	api := &fakeApi{projects: map[string]gitlab.CiVariableList{
+		"a": getVars(),
+		"b": {{Key: "TEST_KEY1", VariableType: "env_var", Value: "OTHER", EnvironmentScope: "staging"}},
+	}}
+	s := service.NewService(api)
+	ctx := context.Background()
+
+	diff, err := s.DiffTargets(ctx, io.Discard, project("a"), project("a"), "staging", "production", "text")
+	assert.NoError(t, err)
+	assert.Len(t, diff, 1)
+	assert.Equal(t, "staging -> production", diff[0].Scope)
+	assert.Len(t, diff[0].Changed, 1)
+	assert.Equal(t, "TEST_KEY3", diff[0].Changed[0].New.Key)
+
+	diff, err = s.DiffTargets(ctx, io.Discard, project("a"), project("b"), "staging", "", "text")
+	assert.NoError(t, err)
+	assert.Len(t, diff, 1)
+	assert.Len(t, diff[0].Removed, 2)
+	assert.Len(t, diff[0].Changed, 1)
+	assert.Empty(t, diff[0].Added)
+}
+
+func TestApply(t *testing.T) {
+	api := &fakeApi{projects: map[string]gitlab.CiVariableList{"a": {
+		{Key: "KEEP", VariableType: "env_var", Value: "1", EnvironmentScope: "*"},
+		{Key: "CHANGE", VariableType: "env_var", Value: "1", EnvironmentScope: "*"},
+		{Key: "REMOVE", VariableType: "env_var", Value: "1", EnvironmentScope: "*"},
+	}}}
+	input := service.Input{
+		Format: "dotenv",
+		Stdin:  bytes.NewBufferString("KEEP=1\nCHANGE=2\nADD=3\n"),
+	}
+	var out bytes.Buffer
+
+	plan, err := service.NewService(api).Apply(context.Background(), &out, project("a"), input, true, true)
+	assert.NoError(t, err)
+	assert.True(t, plan.HasChanges())
+	assert.Contains(t, out.String(), "Plan: 1 to create, 1 to update, 1 to delete, 1 unchanged, 0 skipped.")
+	assert.Len(t, api.projects["a"], 3)
+
+	input.Stdin = bytes.NewBufferString("KEEP=1\nCHANGE=2\nADD=3\n")
+	_, err = service.NewService(api).Apply(context.Background(), &out, project("a"), input, true, false)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, gitlab.CiVariableList{
+		{Key: "KEEP", VariableType: "env_var", Value: "1", EnvironmentScope: "*"},
+		{Key: "CHANGE", VariableType: "env_var", Value: "2", EnvironmentScope: "*"},
+		{Key: "ADD", VariableType: "env_var", Value: "3", EnvironmentScope: "*"},
+	}, api.projects["a"])
+}
+
+func TestUpdateKeepsAttributes(t *testing.T) {
+	api := &fakeApi{projects: map[string]gitlab.CiVariableList{"a": {
+		{Key: "TOKEN", VariableType: "env_var", Value: "old-secret", Protected: true, Masked: true, Description: "deploy", EnvironmentScope: "production"},
+	}}}
+	input := service.Input{Format: "dotenv", Stdin: bytes.NewBufferString("# Scope: production\nTOKEN=new-secret\n")}
+
+	plan, err := service.NewService(api).Update(context.Background(), io.Discard, project("a"), input, false)
+	assert.NoError(t, err)
+	assert.Len(t, plan.Updates, 1)
+	assert.Equal(t, gitlab.CiVariableList{
+		{Key: "TOKEN", VariableType: "env_var", Value: "new-secret", Protected: true, Masked: true, Description: "deploy", EnvironmentScope: "production"},
+	}, api.projects["a"])
+
+	input = service.Input{Format: "json", Stdin: bytes.NewBufferString(`[{"key": "TOKEN", "value": "new-secret", "environment_scope": "production"}]`)}
+	plan, err = service.NewService(api).Apply(context.Background(), io.Discard, project("a"), input, false, true)
+	assert.NoError(t, err)
+	assert.False(t, plan.HasChanges())
+}
+
+func TestSkipHidden(t *testing.T) {
+	api := &fakeApi{projects: map[string]gitlab.CiVariableList{"a": {
+		{Key: "SECRET", VariableType: "env_var", Masked: true, Hidden: true, EnvironmentScope: "production"},
+		{Key: "TOKEN", VariableType: "env_var", Value: "token", EnvironmentScope: "production"},
+	}}}
+	s := service.NewService(api)
+	var out bytes.Buffer
+
+	plan, err := s.UpdateAttributes(context.Background(), &out, project("a"), nil, "production", []string{"protected=true"}, true)
+	assert.NoError(t, err)
+	assert.Len(t, plan.Updates, 1)
+	assert.Len(t, plan.Hidden, 1)
+	assert.Equal(t, "SECRET", plan.Hidden[0].Key)
+	assert.Contains(t, out.String(), "[production] SECRET (skipped, hidden value unknown)")
+
+	input := service.Input{Format: "dotenv", Stdin: bytes.NewBufferString("# Scope: production\n# @hidden @protected\nSECRET=\n")}
+	plan, err = s.Apply(context.Background(), io.Discard, project("a"), input, false, false)
+	assert.NoError(t, err)
+	assert.Empty(t, plan.Updates)
+	assert.Len(t, plan.Hidden, 1)
+	assert.False(t, api.projects["a"][0].Protected)
+
+	input = service.Input{Format: "dotenv", Stdin: bytes.NewBufferString("# Scope: staging\n# @hidden\nSECRET=\n")}
+	plan, err = s.Create(context.Background(), io.Discard, project("a"), input, false)
+	assert.NoError(t, err)
+	assert.Empty(t, plan.Creates)
+	assert.Len(t, plan.Hidden, 1)
+	assert.Len(t, api.projects["a"], 2)
+}
+
+func TestApplyInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	api := &cancelingApi{fakeApi: &fakeApi{projects: map[string]gitlab.CiVariableList{"a": {}}}, cancel: cancel}
+	input := service.Input{Vars: gitlab.CiVariableList{
+		{Key: "A", VariableType: "env_var", Value: "1", EnvironmentScope: "*"},
+		{Key: "B", VariableType: "env_var", Value: "2", EnvironmentScope: "*"},
+		{Key: "C", VariableType: "env_var", Value: "3", EnvironmentScope: "*"},
+	}}
+
+	_, err := service.NewService(api).Apply(ctx, io.Discard, project("a"), input, false, false)
+	var interrupted *service.InterruptedError
+	assert.ErrorAs(t, err, &interrupted)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, "interrupted after 1 of 3 operations: context canceled", err.Error())
+	assert.Equal(t, "Completed:\n+ [*] A\nPending:\n+ [*] B (sent, may have been applied)\n+ [*] C", interrupted.Summary())
+}
+
+func TestCopy(t *testing.T) {
+	source := &fakeApi{projects: map[string]gitlab.CiVariableList{"a": {
+		{Key: "NEW", VariableType: "file", Value: "1", Protected: true, Raw: true, Description: "new", EnvironmentScope: "staging"},
+		{Key: "EXISTING", VariableType: "env_var", Value: "2", EnvironmentScope: "*"},
+		{Key: "SECRET", VariableType: "env_var", Masked: true, Hidden: true, EnvironmentScope: "*"},
+	}}}
+	destination := &fakeApi{projects: map[string]gitlab.CiVariableList{"b": {
+		{Key: "EXISTING", VariableType: "env_var", Value: "old", EnvironmentScope: "*"},
+	}}}
+
+	var out bytes.Buffer
+	plan, err := service.Copy(context.Background(), &out, service.NewService(source), project("a"), service.NewService(destination), project("b"), service.CopyOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, out.String())
+	assert.Len(t, plan.Creates, 1)
+	assert.Len(t, plan.Skips, 1)
+	assert.Len(t, plan.Hidden, 1)
+	assert.Equal(t, gitlab.CiVariableList{
+		{Key: "EXISTING", VariableType: "env_var", Value: "old", EnvironmentScope: "*"},
+		{Key: "NEW", VariableType: "file", Value: "1", Protected: true, Raw: true, Description: "new", EnvironmentScope: "staging"},
+	}, destination.projects["b"])
+
+	plan, err = service.Copy(context.Background(), io.Discard, service.NewService(source), project("a"), service.NewService(destination), project("b"), service.CopyOptions{Overwrite: true})
+	assert.NoError(t, err)
+	assert.Len(t, plan.Updates, 1)
+	assert.Equal(t, "2", destination.projects["b"][0].Value)
+}
+
+func project(path string) service.Target {
+	return service.Target{Kind: service.ProjectTarget, Path: path}
+}
+
+// fakeApi serves project variables from memory.
+type fakeApi struct {
+	gitlab.Api
+	projects map[string]gitlab.CiVariableList
+}
+
+func (f *fakeApi) GetProjectVars(ctx context.Context, project string) (gitlab.CiVariableList, error) {
+	vars, found := f.projects[project]
+	if !found {
+		return nil, &gitlab.ApiError{StatusCode: 404, Message: "404 Project Not Found"}
+	}
+	return vars, nil
+}
+
+func (f *fakeApi) CreateVar(ctx context.Context, project string, variable gitlab.CiVariable) (*gitlab.CiVariable, error) {
+	f.projects[project] = append(f.projects[project], variable)
+	return &variable, nil
+}
+
+func (f *fakeApi) UpdateVar(ctx context.Context, project string, variable gitlab.CiVariable) (*gitlab.CiVariable, error) {
+	for i, existing := range f.projects[project] {
+		if existing.Key == variable.Key && existing.EnvironmentScope == variable.EnvironmentScope {
+			f.projects[project][i] = variable
+		}
+	}
+	return &variable, nil
+}
+
+func (f *fakeApi) DeleteVar(ctx context.Context, project string, variable gitlab.CiVariable) error {
+	var remaining gitlab.CiVariableList
+	for _, existing := range f.projects[project] {
+		if existing.Key != variable.Key || existing.EnvironmentScope != variable.EnvironmentScope {
+			remaining = append(remaining, existing)
+		}
+	}
+	f.projects[project] = remaining
+	return nil
+}
+
+// cancelingApi cancels the context during the second create request.
+type cancelingApi struct {
+	*fakeApi
+	cancel  func()
+	creates int
+}
+
+func (c *cancelingApi) CreateVar(ctx context.Context, project string, variable gitlab.CiVariable) (*gitlab.CiVariable, error) {
+	c.creates++
+	if c.creates == 2 {
+		c.cancel()
+		return nil, ctx.Err()
+	}
+	return c.fakeApi.CreateVar(ctx, project, variable)
+}
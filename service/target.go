@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ninogresenz/civar/gitlab"
+)
+
+type TargetKind int
+
+const (
+	ProjectTarget TargetKind = iota
+	GroupTarget
+	InstanceTarget
+)
+
+// Target identifies the Gitlab resource owning a set of CI/CD variables.
+type Target struct {
+	Kind TargetKind
+	Path string
+}
+
+func (t Target) String() string {
+	switch t.Kind {
+	case GroupTarget:
+		return fmt.Sprintf("group %s", t.Path)
+	case InstanceTarget:
+		return "instance"
+	default:
+		return fmt.Sprintf("project %s", t.Path)
+	}
+}
+
+// checkTarget makes sure the variables can be stored in the target.
+// Instance variables have no environment scope, so only the * scope is accepted.
+func checkTarget(target Target, data gitlab.CiVariableList) error {
+	if target.Kind != InstanceTarget {
+		return nil
+	}
+	for _, variable := range data {
+		if variable.EnvironmentScope != AllScope {
+			return fmt.Errorf("instance variables do not support environment scopes: [key: %s, scope: %s]", variable.Key, variable.EnvironmentScope)
+		}
+	}
+	return nil
+}
+
+// targetError adds a hint to errors caused by missing admin rights on instance variables.
+func targetError(target Target, err error) error {
+	if target.Kind == InstanceTarget && errors.Is(err, gitlab.ErrForbidden) {
+		return fmt.Errorf("instance variables can only be managed with an administrator token: %w", err)
+	}
+	return err
+}
+
+func (s *service) getVars(ctx context.Context, target Target) (data gitlab.CiVariableList, err error) {
+	switch target.Kind {
+	case GroupTarget:
+		data, err = s.api.GetGroupVars(ctx, target.Path)
+	case InstanceTarget:
+		data, err = s.api.GetInstanceVars(ctx)
+		for i := range data {
+			data[i].EnvironmentScope = AllScope
+		}
+	default:
+		data, err = s.api.GetProjectVars(ctx, target.Path)
+	}
+	return data, targetError(target, err)
+}
+
+func (s *service) createVar(ctx context.Context, target Target, variable gitlab.CiVariable) (created *gitlab.CiVariable, err error) {
+	switch target.Kind {
+	case GroupTarget:
+		created, err = s.api.CreateGroupVar(ctx, target.Path, variable)
+	case InstanceTarget:
+		created, err = s.api.CreateInstanceVar(ctx, variable)
+	default:
+		created, err = s.api.CreateVar(ctx, target.Path, variable)
+	}
+	return created, targetError(target, err)
+}
+
+func (s *service) updateVar(ctx context.Context, target Target, variable gitlab.CiVariable) (updated *gitlab.CiVariable, err error) {
+	switch target.Kind {
+	case GroupTarget:
+		updated, err = s.api.UpdateGroupVar(ctx, target.Path, variable)
+	case InstanceTarget:
+		updated, err = s.api.UpdateInstanceVar(ctx, variable)
+	default:
+		updated, err = s.api.UpdateVar(ctx, target.Path, variable)
+	}
+	return updated, targetError(target, err)
+}
+
+func (s *service) deleteVar(ctx context.Context, target Target, variable gitlab.CiVariable) (err error) {
+	switch target.Kind {
+	case GroupTarget:
+		err = s.api.DeleteGroupVar(ctx, target.Path, variable)
+	case InstanceTarget:
+		err = s.api.DeleteInstanceVar(ctx, variable)
+	default:
+		err = s.api.DeleteVar(ctx, target.Path, variable)
+	}
+	return targetError(target, err)
+}
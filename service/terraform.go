@@ -0,0 +1,203 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ninogresenz/civar/gitlab"
+)
+
+// terraformValues is the terraform variable holding the values of the tfvars file.
+const terraformValues = "ci_variable_values"
+
+// TerraformPrinter prints the variables as resources of the Gitlab terraform provider
+// with import blocks for the existing variables. With tfvars, values are referenced
+// from a sensitive map variable filled by the output of TfVars instead of being inlined.
+// Changes to the values of hidden variables are ignored by terraform.
+type terraformPrinter struct {
+	target Target
+	tfvars bool
+}
+
+func (p terraformPrinter) Print(data gitlab.CiVariableList) (string, error) {
+	data = sortByScopeAndKey(data)
+	var blocks []string
+	if p.tfvars {
+		blocks = append(blocks, fmt.Sprintf("variable %q {\n  type      = map(string)\n  sensitive = true\n}", terraformValues))
+	}
+	names := terraformNames(data)
+	for i, variable := range data {
+		resource := fmt.Sprintf("%s.%s", p.resourceType(), names[i])
+		var attributes [][2]string
+		switch p.target.Kind {
+		case ProjectTarget:
+			attributes = append(attributes, [2]string{"project", hclString(p.target.Path)})
+		case GroupTarget:
+			attributes = append(attributes, [2]string{"group", hclString(p.target.Path)})
+		}
+		value := hclString(variable.Value)
+		if p.tfvars && !variable.Hidden {
+			value = fmt.Sprintf("var.%s[%s]", terraformValues, hclString(names[i]))
+		}
+		attributes = append(attributes,
+			[2]string{"key", hclString(variable.Key)},
+			[2]string{"value", value},
+			[2]string{"variable_type", hclString(variable.VariableType)},
+			[2]string{"protected", fmt.Sprint(variable.Protected)},
+			[2]string{"masked", fmt.Sprint(variable.Masked)},
+			[2]string{"raw", fmt.Sprint(variable.Raw)},
+		)
+		if p.target.Kind != InstanceTarget {
+			attributes = append(attributes, [2]string{"environment_scope", hclString(variable.EnvironmentScope)})
+		}
+		if len(variable.Description) > 0 {
+			attributes = append(attributes, [2]string{"description", hclString(variable.Description)})
+		}
+
+		var b strings.Builder
+		if variable.Hidden {
+			b.WriteString("# hidden in Gitlab, changes to the value are ignored\n")
+		}
+		b.WriteString(fmt.Sprintf("resource %q %q {\n", p.resourceType(), names[i]))
+		b.WriteString(hclAttributes(attributes))
+		if variable.Hidden {
+			b.WriteString("\n  lifecycle {\n    ignore_changes = [value]\n  }\n")
+		}
+		b.WriteString("}\n\n")
+		b.WriteString("import {\n")
+		b.WriteString(hclAttributes([][2]string{{"to", resource}, {"id", hclString(p.importId(variable))}}))
+		b.WriteString("}")
+		blocks = append(blocks, b.String())
+	}
+	return strings.Join(blocks, "\n\n"), nil
+}
+
+// TfVars prints the values of the variables for the map variable referenced by Print with tfvars.
+// Hidden variables are left out.
+func (p terraformPrinter) TfVars(data gitlab.CiVariableList) string {
+	data = sortByScopeAndKey(data)
+	names := terraformNames(data)
+	var attributes [][2]string
+	for i, variable := range data {
+		if variable.Hidden {
+			continue
+		}
+		attributes = append(attributes, [2]string{hclString(names[i]), hclString(variable.Value)})
+	}
+	return fmt.Sprintf("%s = {\n%s}", terraformValues, hclAttributes(attributes))
+}
+
+func (p terraformPrinter) resourceType() string {
+	switch p.target.Kind {
+	case GroupTarget:
+		return "gitlab_group_variable"
+	case InstanceTarget:
+		return "gitlab_instance_variable"
+	default:
+		return "gitlab_project_variable"
+	}
+}
+
+// importId identifies an existing variable as expected by the import of the provider.
+func (p terraformPrinter) importId(variable gitlab.CiVariable) string {
+	if p.target.Kind == InstanceTarget {
+		return variable.Key
+	}
+	return fmt.Sprintf("%s:%s:%s", p.target.Path, variable.Key, variable.EnvironmentScope)
+}
+
+// terraformNames creates unique resource names from scope and key, e.g. staging_db_password.
+func terraformNames(data gitlab.CiVariableList) []string {
+	names := make([]string, len(data))
+	used := map[string]bool{}
+	for i, variable := range data {
+		scope := variable.EnvironmentScope
+		if scope == AllScope {
+			scope = "all"
+		}
+		base := terraformIdentifier(scope + "_" + variable.Key)
+		name := base
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+func terraformIdentifier(name string) string {
+	identifier := []rune(strings.ToLower(name))
+	for i, r := range identifier {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' || r > unicode.MaxASCII {
+			identifier[i] = '_'
+		}
+	}
+	if len(identifier) == 0 || unicode.IsDigit(identifier[0]) {
+		return "_" + string(identifier)
+	}
+	return string(identifier)
+}
+
+// hclAttributes renders name = value lines aligned like terraform fmt does.
+func hclAttributes(attributes [][2]string) string {
+	width := 0
+	for _, attribute := range attributes {
+		if len(attribute[0]) > width {
+			width = len(attribute[0])
+		}
+	}
+	var b strings.Builder
+	for _, attribute := range attributes {
+		b.WriteString(fmt.Sprintf("  %-*s = %s\n", width, attribute[0], attribute[1]))
+	}
+	return b.String()
+}
+
+// hclString quotes a string for HCL. Template sequences are escaped, so values are taken literally.
+func hclString(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	runes := []rune(value)
+	for i, r := range runes {
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		switch {
+		case r == '"':
+			b.WriteString(`\"`)
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case (r == '$' || r == '%') && next == '{':
+			b.WriteRune(r)
+			b.WriteRune(r)
+		case unicode.IsControl(r):
+			b.WriteString(fmt.Sprintf(`\u%04x`, r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// sortByScopeAndKey returns a copy of the variables ordered like the yaml format.
+func sortByScopeAndKey(data gitlab.CiVariableList) gitlab.CiVariableList {
+	sorted := append(gitlab.CiVariableList{}, data...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].EnvironmentScope != sorted[j].EnvironmentScope {
+			return scopeLess(sorted[i].EnvironmentScope, sorted[j].EnvironmentScope)
+		}
+		return sorted[i].Key < sorted[j].Key
+	})
+	return sorted
+}
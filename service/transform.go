@@ -0,0 +1,146 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ninogresenz/civar/gitlab"
+)
+
+// KeyTransform rewrites the key of a variable.
+type KeyTransform func(key string) string
+
+// KeyTransforms are applied in order.
+type KeyTransforms []KeyTransform
+
+// AddKeyPrefix prepends prefix to keys which do not start with it yet.
+func AddKeyPrefix(prefix string) KeyTransform {
+	return func(key string) string {
+		if strings.HasPrefix(key, prefix) {
+			return key
+		}
+		return prefix + key
+	}
+}
+
+// StripKeyPrefix removes prefix from the start of keys.
+func StripKeyPrefix(prefix string) KeyTransform {
+	return func(key string) string {
+		return strings.TrimPrefix(key, prefix)
+	}
+}
+
+// AddKeySuffix appends suffix to keys which do not end with it yet.
+func AddKeySuffix(suffix string) KeyTransform {
+	return func(key string) string {
+		if strings.HasSuffix(key, suffix) {
+			return key
+		}
+		return key + suffix
+	}
+}
+
+// StripKeySuffix removes suffix from the end of keys.
+func StripKeySuffix(suffix string) KeyTransform {
+	return func(key string) string {
+		return strings.TrimSuffix(key, suffix)
+	}
+}
+
+// RenameKey replaces all matches of pattern, the replacement may refer to groups like $1.
+func RenameKey(pattern *regexp.Regexp, replacement string) KeyTransform {
+	return func(key string) string {
+		return pattern.ReplaceAllString(key, replacement)
+	}
+}
+
+// K8sImport is the preset adding the K8S_SECRET_ prefix to imported keys.
+func K8sImport() KeyTransforms {
+	return KeyTransforms{AddKeyPrefix(K8sPrefix)}
+}
+
+// K8sExport is the preset removing the K8S_SECRET_ prefix from exported keys.
+func K8sExport() KeyTransforms {
+	return KeyTransforms{StripKeyPrefix(K8sPrefix)}
+}
+
+// ParseKeyTransforms reads transforms given as name=argument:
+// add-prefix=APP_, strip-prefix=APP_, add-suffix=_V2, strip-suffix=_V2,
+// rename=^OLD_(.*)=NEW_$1 (split at the last =), upper and lower.
+func ParseKeyTransforms(specs []string) (KeyTransforms, error) {
+	var transforms KeyTransforms
+	for _, spec := range specs {
+		name, argument, _ := strings.Cut(spec, "=")
+		transform, err := keyTransform(strings.TrimSpace(name), argument)
+		if err != nil {
+			return nil, err
+		}
+		transforms = append(transforms, transform)
+	}
+	return transforms, nil
+}
+
+func keyTransform(name string, argument string) (KeyTransform, error) {
+	switch name {
+	case "upper":
+		return strings.ToUpper, nil
+	case "lower":
+		return strings.ToLower, nil
+	}
+	if len(argument) == 0 {
+		return nil, fmt.Errorf("key transform must look like name=argument: %s", name)
+	}
+	switch name {
+	case "add-prefix":
+		return AddKeyPrefix(argument), nil
+	case "strip-prefix":
+		return StripKeyPrefix(argument), nil
+	case "add-suffix":
+		return AddKeySuffix(argument), nil
+	case "strip-suffix":
+		return StripKeySuffix(argument), nil
+	case "rename":
+		separator := strings.LastIndex(argument, "=")
+		if separator < 1 {
+			return nil, fmt.Errorf("rename must look like rename=pattern=replacement: %s", argument)
+		}
+		pattern, err := regexp.Compile(argument[:separator])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rename pattern: %w", err)
+		}
+		return RenameKey(pattern, argument[separator+1:]), nil
+	default:
+		return nil, fmt.Errorf("unknown key transform: %s", name)
+	}
+}
+
+// Key applies all transforms to key.
+func (t KeyTransforms) Key(key string) string {
+	for _, transform := range t {
+		key = transform(key)
+	}
+	return key
+}
+
+// Keys returns the transformed keys.
+func (t KeyTransforms) Keys(keys []string) []string {
+	transformed := make([]string, len(keys))
+	for i, key := range keys {
+		transformed[i] = t.Key(key)
+	}
+	return transformed
+}
+
+// Apply returns a copy of the variables with transformed keys.
+func (t KeyTransforms) Apply(data gitlab.CiVariableList) gitlab.CiVariableList {
+	if data == nil {
+		return nil
+	}
+	transformed := make(gitlab.CiVariableList, len(data))
+	for i, variable := range data {
+		variable.Key = t.Key(variable.Key)
+		transformed[i] = variable
+	}
+	return transformed
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ninogresenz/civar/gitlab"
+)
+
+// yamlVariable holds the attributes of a variable in the yaml format.
+// All attributes are printed, so they replace the attributes of an existing variable when applied.
+type yamlVariable struct {
+	Value        string `yaml:"value"`
+	VariableType string `yaml:"variable_type"`
+	Protected    bool   `yaml:"protected"`
+	Masked       bool   `yaml:"masked"`
+	Hidden       bool   `yaml:"hidden,omitempty"`
+	Raw          bool   `yaml:"raw"`
+	Description  string `yaml:"description"`
+}
+
+// YamlPrinter prints the variables grouped by scope and key with all their attributes.
+// Multi-line values are printed as literal blocks.
+type yamlPrinter struct{}
+
+func (p yamlPrinter) Print(data gitlab.CiVariableList) (string, error) {
+	scopes := map[string]gitlab.CiVariableList{}
+	var scopeIds []string
+	for _, variable := range data {
+		if _, present := scopes[variable.EnvironmentScope]; !present {
+			scopeIds = append(scopeIds, variable.EnvironmentScope)
+		}
+		scopes[variable.EnvironmentScope] = append(scopes[variable.EnvironmentScope], variable)
+	}
+	sort.Slice(scopeIds, func(i, j int) bool {
+		return scopeLess(scopeIds[i], scopeIds[j])
+	})
+
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, scopeId := range scopeIds {
+		variables := scopes[scopeId]
+		sortByKey(variables)
+		scope := &yaml.Node{Kind: yaml.MappingNode}
+		for _, variable := range variables {
+			node, err := yamlNode(variable)
+			if err != nil {
+				return "", err
+			}
+			scope.Content = append(scope.Content, yamlString(variable.Key), node)
+		}
+		root.Content = append(root.Content, yamlString(scopeId), scope)
+	}
+
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return "", fmt.Errorf("could not marshal variables to yaml: %w", err)
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+func yamlNode(variable gitlab.CiVariable) (*yaml.Node, error) {
+	attributes := yamlVariable{
+		Value:        variable.Value,
+		Protected:    variable.Protected,
+		Masked:       variable.Masked,
+		Hidden:       variable.Hidden,
+		Raw:          variable.Raw,
+		Description:  variable.Description,
+		VariableType: variable.VariableType,
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(attributes); err != nil {
+		return nil, fmt.Errorf("could not marshal variable %s to yaml: %w", variable.Key, err)
+	}
+	node.Content[1] = yamlString(variable.Value)
+	return node, nil
+}
+
+// yamlString creates a string node, multi-line strings are written as literal blocks.
+func yamlString(value string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	if strings.Contains(value, "\n") {
+		node.Style = yaml.LiteralStyle
+	}
+	return node
+}
+
+// ParseYaml reads variables in the format of the yaml printer. A variable may also be written
+// as KEY: value, attributes missing in the input are marked as omitted.
+func ParseYaml(input []byte) ([]gitlab.CiVariable, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(input, &document); err != nil {
+		return nil, fmt.Errorf("could not parse yaml: %w", err)
+	}
+	if len(document.Content) == 0 {
+		return nil, nil
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("could not parse yaml: expected a map of scopes in line %d", root.Line)
+	}
+	var data []gitlab.CiVariable
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		scope, variables := root.Content[i].Value, root.Content[i+1]
+		if variables.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("could not parse yaml: expected a map of keys in scope %s in line %d", scope, variables.Line)
+		}
+		for j := 0; j+1 < len(variables.Content); j += 2 {
+			key, node := variables.Content[j].Value, variables.Content[j+1]
+			attributes := yamlVariable{VariableType: envVariableType}
+			omitted := gitlab.AllAttributes
+			switch node.Kind {
+			case yaml.ScalarNode:
+				attributes.Value = node.Value
+			case yaml.MappingNode:
+				if err := node.Decode(&attributes); err != nil {
+					return nil, fmt.Errorf("could not parse yaml variable %s in scope %s: %w", key, scope, err)
+				}
+				var names []string
+				for k := 0; k < len(node.Content); k += 2 {
+					names = append(names, node.Content[k].Value)
+				}
+				omitted = omittedAttributes(names)
+			default:
+				return nil, fmt.Errorf("could not parse yaml: expected a value or map of attributes for %s in line %d", key, node.Line)
+			}
+			data = append(data, gitlab.CiVariable{
+				Key:              key,
+				VariableType:     attributes.VariableType,
+				Value:            attributes.Value,
+				Protected:        attributes.Protected,
+				Masked:           attributes.Masked,
+				Hidden:           attributes.Hidden,
+				Raw:              attributes.Raw,
+				Description:      attributes.Description,
+				EnvironmentScope: scope,
+				Omitted:          omitted,
+			})
+		}
+	}
+	return data, nil
+}